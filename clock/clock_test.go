@@ -0,0 +1,31 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Unix(0, 0)
+	f := NewFake(start)
+	a := f.After(time.Second)
+	b := f.After(2 * time.Second)
+
+	f.Advance(time.Second)
+	select {
+	case now := <-a:
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("Wrong time: %v", now)
+		}
+	default:
+		t.Error("Timer did not fire")
+	}
+	select {
+	case <-b:
+		t.Error("Timer fired early")
+	default:
+	}
+	if n := f.Waiters(); n != 1 {
+		t.Errorf("Wrong waiters: %d", n)
+	}
+}
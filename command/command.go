@@ -0,0 +1,282 @@
+// Package command routes chat commands such as "!so someone" to handlers.
+package command
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+// A Level is a permission level, derived from a user's badges. Higher levels
+// include the lower ones.
+type Level int
+
+const (
+	Everyone Level = iota
+	Subscriber
+	VIP
+	Moderator
+	Broadcaster
+)
+
+func (l Level) String() string {
+	switch l {
+	case Everyone:
+		return "everyone"
+	case Subscriber:
+		return "subscriber"
+	case VIP:
+		return "vip"
+	case Moderator:
+		return "moderator"
+	case Broadcaster:
+		return "broadcaster"
+	}
+	return "unknown"
+}
+
+// LevelOf returns the permission level of a message's sender.
+func LevelOf(p *twitch.Privmsg) Level {
+	switch {
+	case p.Badges.Has("broadcaster"):
+		return Broadcaster
+	case p.Badges.Has("moderator"), p.Message != nil && p.Message.Tags["mod"] == "1":
+		return Moderator
+	case p.Badges.Has("vip"):
+		return VIP
+	case p.Badges.Has("subscriber"), p.Badges.Has("founder"):
+		return Subscriber
+	}
+	return Everyone
+}
+
+// A Command is a registered chat command.
+type Command struct {
+	Name    string
+	Aliases []string
+
+	// Level is the permission level required to use the command.
+	Level Level
+
+	// Cooldown is the time between uses of the command in a channel, and
+	// UserCooldown the time between uses by the same user in a channel.
+	Cooldown     time.Duration
+	UserCooldown time.Duration
+
+	Handler func(*Context) error
+}
+
+// A Sender sends messages to chat, e.g. an ircon.IRCon or ircon.Client.
+type Sender interface {
+	Say(ctx context.Context, channel, text string) error
+	Reply(ctx context.Context, parent *irc.Message, text string) error
+}
+
+// A Context is a single use of a command.
+type Context struct {
+	Command *Command
+	Name    string // The name or alias used
+	Args    []string
+	Rest    string // The unsplit arguments
+	Level   Level
+	Privmsg *twitch.Privmsg
+
+	sender Sender
+}
+
+// Say sends a message to the channel the command was used in.
+func (c *Context) Say(ctx context.Context, text string) error {
+	return c.sender.Say(ctx, c.Privmsg.Channel, text)
+}
+
+// Reply sends a threaded reply to the command's message.
+func (c *Context) Reply(ctx context.Context, text string) error {
+	return c.sender.Reply(ctx, c.Privmsg.Message, text)
+}
+
+var (
+	ErrCommandExists = errors.New("Command already exists")
+	ErrPermission    = errors.New("Permission denied")
+	ErrCooldown      = errors.New("Command on cooldown")
+)
+
+// A Router is an ircon.Handler calling the commands used in PRIVMSGs. The
+// commands are called synchronously, and should not block the connection, e.g.
+// by using an ircon.Dispatcher.
+type Router struct {
+	// Prefixes start a command. If empty, "!" is used.
+	Prefixes []string
+
+	// OnError is called when a command is refused or its Handler failed.
+	OnError func(c *Context, err error)
+
+	// Clock is used for cooldowns. If nil, clock.System is used.
+	Clock clock.Clock
+
+	sender Sender
+
+	mu       sync.Mutex
+	commands map[string]*Command
+	// until holds the end of cooldowns by channel, command and user
+	until map[string]time.Time
+	prune int
+}
+
+// NewRouter creates a Router replying through s.
+func NewRouter(s Sender, prefixes ...string) *Router {
+	return &Router{
+		Prefixes: prefixes,
+		sender:   s,
+	}
+}
+
+// Add registers a command under its name and aliases, which are matched
+// case-insensitively.
+func (r *Router) Add(cmd *Command) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.commands == nil {
+		r.commands = make(map[string]*Command)
+	}
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, name := range names {
+		if _, ok := r.commands[strings.ToLower(name)]; ok {
+			return ErrCommandExists
+		}
+	}
+	for _, name := range names {
+		r.commands[strings.ToLower(name)] = cmd
+	}
+	return nil
+}
+
+// Remove unregisters a command by its name or an alias.
+func (r *Router) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cmd := r.commands[strings.ToLower(name)]
+	for k, v := range r.commands {
+		if v == cmd {
+			delete(r.commands, k)
+		}
+	}
+}
+
+func (r *Router) Connected()         {}
+func (r *Router) Disconnected(error) {}
+
+func (r *Router) Message(msg *irc.Message) {
+	if msg.Command == "PRIVMSG" {
+		r.Privmsg(twitch.ParsePrivmsg(msg))
+	}
+}
+
+// Privmsg handles a chat message, e.g. as ircon.Mux.OnPrivmsg.
+func (r *Router) Privmsg(p *twitch.Privmsg) {
+	if p.Action {
+		return
+	}
+	name, rest, ok := r.parse(p.Text)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	cmd := r.commands[strings.ToLower(name)]
+	r.mu.Unlock()
+	if cmd == nil {
+		return
+	}
+
+	c := &Context{
+		Command: cmd,
+		Name:    name,
+		Args:    Split(rest),
+		Rest:    rest,
+		Level:   LevelOf(p),
+		Privmsg: p,
+		sender:  r.sender,
+	}
+	err := ErrPermission
+	if c.Level >= cmd.Level {
+		err = r.cooldown(cmd, p)
+	}
+	if err == nil && cmd.Handler != nil {
+		err = cmd.Handler(c)
+	}
+	if err != nil && r.OnError != nil {
+		r.OnError(c, err)
+	}
+}
+
+// parse splits a message into the command name and its arguments.
+func (r *Router) parse(text string) (name, rest string, ok bool) {
+	// Chat clients append U+E0000 to bypass the duplicate message check
+	text = strings.TrimSpace(strings.TrimSuffix(text, "\U000E0000"))
+	prefixes := r.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = []string{"!"}
+	}
+	for _, prefix := range prefixes {
+		if prefix == "" || !strings.HasPrefix(text, prefix) {
+			continue
+		}
+		text = text[len(prefix):]
+		if n := strings.IndexAny(text, " \t"); n >= 0 {
+			name, rest = text[:n], strings.TrimSpace(text[n:])
+		} else {
+			name = text
+		}
+		return name, rest, name != ""
+	}
+	return "", "", false
+}
+
+// cooldown records a use of cmd, or returns ErrCooldown if it is too soon.
+func (r *Router) cooldown(cmd *Command, p *twitch.Privmsg) error {
+	clk := r.Clock
+	if clk == nil {
+		clk = clock.System
+	}
+	now := clk.Now()
+	key := p.Channel + " " + cmd.Name
+	userKey := key + " " + p.User
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Before(r.until[key]) || now.Before(r.until[userKey]) {
+		return ErrCooldown
+	}
+	if r.until == nil || len(r.until) >= r.prune {
+		r.pruneCooldowns(now)
+	}
+	if cmd.Cooldown > 0 {
+		r.until[key] = now.Add(cmd.Cooldown)
+	}
+	if cmd.UserCooldown > 0 {
+		r.until[userKey] = now.Add(cmd.UserCooldown)
+	}
+	return nil
+}
+
+// pruneCooldowns forgets expired cooldowns, so per-user cooldowns do not
+// accumulate.
+func (r *Router) pruneCooldowns(now time.Time) {
+	if r.until == nil {
+		r.until = make(map[string]time.Time)
+	}
+	for k, t := range r.until {
+		if !now.Before(t) {
+			delete(r.until, k)
+		}
+	}
+	r.prune = 2 * len(r.until)
+	if r.prune < 1024 {
+		r.prune = 1024
+	}
+}
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/ircon"
+)
+
+var _ ircon.Handler = (*Router)(nil)
+
+type sent struct {
+	said, replied []string
+}
+
+func (s *sent) Say(ctx context.Context, channel, text string) error {
+	s.said = append(s.said, channel+" "+text)
+	return nil
+}
+
+func (s *sent) Reply(ctx context.Context, parent *irc.Message, text string) error {
+	s.replied = append(s.replied, parent.Tags["id"]+" "+text)
+	return nil
+}
+
+func privmsg(badges, user, text string) *irc.Message {
+	return irc.ParseMessage("@badges=" + badges + ";id=m1 :" + user + "!" + user + "@" + user +
+		".tmi.twitch.tv PRIVMSG #chan :" + text)
+}
+
+func TestSplit(t *testing.T) {
+	for s, want := range map[string][]string{
+		"":                      nil,
+		"  a  b ":               {"a", "b"},
+		`a "b c" d`:             {"a", "b c", "d"},
+		`'it"s' x`:              {`it"s`, "x"},
+		`"say \"hi\""`:          {`say "hi"`},
+		`""`:                    {""},
+		`a"b c"d`:               {"ab cd"},
+		`"unterminated quote x`: {"unterminated quote x"},
+	} {
+		if got := Split(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("Split(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	s := &sent{}
+	clk := clock.NewFake(time.Unix(0, 0))
+	r := NewRouter(s, "!", "?")
+	r.Clock = clk
+	var errs []error
+	r.OnError = func(c *Context, err error) { errs = append(errs, err) }
+
+	var got []*Context
+	err := r.Add(&Command{
+		Name:         "so",
+		Aliases:      []string{"shoutout"},
+		Level:        VIP,
+		UserCooldown: time.Minute,
+		Handler: func(c *Context) error {
+			got = append(got, c)
+			return c.Reply(context.Background(), "check out "+c.Args[0])
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add(&Command{Name: "SO"}); err != ErrCommandExists {
+		t.Errorf("Wrong error: %v", err)
+	}
+	r.Add(&Command{
+		Name:     "hi",
+		Cooldown: time.Minute,
+		Handler: func(c *Context) error {
+			return c.Say(context.Background(), "hello "+c.Privmsg.DisplayName)
+		},
+	})
+
+	r.Message(privmsg("vip/1", "alice", `?ShoutOut "bob" now`))
+	r.Message(privmsg("subscriber/12", "carol", "!so bob"))
+	r.Message(privmsg("moderator/1", "dave", "!so eve \U000E0000"))
+	r.Message(privmsg("moderator/1", "dave", "!so eve"))
+	r.Message(privmsg("", "frank", "!unknown"))
+	r.Message(privmsg("", "frank", "hi"))
+
+	if len(got) != 2 {
+		t.Fatalf("Wrong calls: %d", len(got))
+	}
+	if c := got[0]; c.Name != "ShoutOut" || c.Rest != `"bob" now` || !reflect.DeepEqual(c.Args, []string{"bob", "now"}) || c.Level != VIP {
+		t.Errorf("Wrong context: %+v", c)
+	}
+	if c := got[1]; !reflect.DeepEqual(c.Args, []string{"eve"}) || c.Level != Moderator {
+		t.Errorf("Wrong context: %+v", c)
+	}
+	if !reflect.DeepEqual(s.replied, []string{"m1 check out bob", "m1 check out eve"}) {
+		t.Errorf("Wrong replies: %q", s.replied)
+	}
+	if !reflect.DeepEqual(errs, []error{ErrPermission, ErrCooldown}) {
+		t.Errorf("Wrong errors: %v", errs)
+	}
+
+	// The channel cooldown applies to all users
+	r.Message(privmsg("", "frank", "!hi"))
+	r.Message(privmsg("broadcaster/1", "grace", "!hi"))
+	clk.Advance(time.Minute)
+	r.Message(privmsg("broadcaster/1", "grace", "!hi"))
+	if len(s.said) != 2 || errs[2] != ErrCooldown {
+		t.Errorf("Wrong messages: %q %v", s.said, errs)
+	}
+}
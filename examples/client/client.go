@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
@@ -16,10 +16,11 @@ import (
 type handler struct {
 	Con      *ircon.IRCon
 	Channels []string
+	Log      *slog.Logger
 }
 
 func (h *handler) Connected() {
-	fmt.Println("#", time.Now(), "Connected")
+	h.Log.Info("Connected")
 	// 50/15
 	go func() {
 		N := 50
@@ -29,19 +30,19 @@ func (h *handler) Connected() {
 				end = len(h.Channels)
 			}
 			clist := strings.Join(h.Channels[i:end], ",")
-			fmt.Println("# Join:", clist)
+			h.Log.Info("Joining", "channels", clist)
 			h.Con.Send("JOIN " + clist)
 			time.Sleep(time.Second * 16)
 		}
 	}()
 }
 
-func (handler) Disconnected(err error) {
-	fmt.Println("#", time.Now(), "Disconnected:", err)
+func (h *handler) Disconnected(err error) {
+	h.Log.Info("Disconnected", "err", err)
 }
 
-func (handler) Message(msg *ircon.Message) {
-	fmt.Println("MSG:", msg.Raw())
+func (h *handler) Message(msg *ircon.Message) {
+	h.Log.Info("Message", "command", msg.Command, "args", msg.Args)
 }
 
 func main() {
@@ -56,15 +57,23 @@ func main() {
 		server = ircon.DefaultServer
 	}
 
-	dialer, err := irc.New(server)
+	level := slog.LevelInfo
+	if os.Getenv("DEBUG") != "" {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	dialer, err := irc.New(server, irc.WithLogger{Logger: logger})
 	if err != nil {
 		panic(err)
 	}
 
 	con := ircon.New(dialer, ircon.TwitchHandshaker(nick, passwd))
 	con.Server = server
+	con.Logger = logger
 	h := &handler{
 		Con: con,
+		Log: logger,
 	}
 	if channel != "" {
 		chans := strings.Split(channel, ",")
@@ -1,5 +1,5 @@
 module raccatta.cc/tmi
 
-go 1.16
+go 1.21
 
 require github.com/gorilla/websocket v1.4.2
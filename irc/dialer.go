@@ -18,6 +18,11 @@ import (
 // The PING interval appears to be around 4 to 5 minutes.
 const deadline = 6 * 60 * time.Second
 
+// dialTimeout limits dialing, including the TLS and websocket handshakes.
+const dialTimeout = 30 * time.Second
+
+var ErrDialTimeout = errors.New("Dial timed out")
+
 // A Conn is an IRC connection.
 type Conn interface {
 	Read() (*Message, error)
@@ -33,26 +38,34 @@ type Dialer interface {
 }
 
 // New creates a new Dialer from a URL.
-func New(addr string) (Dialer, error) {
+func New(addr string, options ...Option) (Dialer, error) {
 	u, err := url.Parse(addr)
 	if err != nil {
 		return nil, err
 	}
 
+	var t transport
+	for _, opt := range options {
+		opt.apply(&t)
+	}
+
 	switch u.Scheme {
-	case "ws":
-		return websocketTransport{
-			addr:   addr,
-			dialer: websocket.DefaultDialer,
-		}, nil
-	case "wss":
+	case "ws", "wss":
+		dialer := websocket.DefaultDialer
+		if t.config != nil {
+			d := *dialer
+			d.TLSClientConfig = t.config
+			dialer = &d
+		}
 		return websocketTransport{
-			addr:   addr,
-			dialer: websocket.DefaultDialer,
+			addr:      addr,
+			dialer:    dialer,
+			transport: t,
 		}, nil
 	case "ircs":
 		return tlsTransport{
-			addr: defaultPort(u.Host, "6697"),
+			addr:      defaultPort(u.Host, "6697"),
+			transport: t,
 		}, nil
 	}
 
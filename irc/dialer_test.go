@@ -0,0 +1,49 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+)
+
+func TestDialTimeout(t *testing.T) {
+	// The server accepts, but never answers the TLS handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	d := NewTLS(l.Addr().String(), WithClock{clk})
+	errs := make(chan error, 1)
+	go func() {
+		_, err := d.Dial(context.Background())
+		errs <- err
+	}()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("Dial returned early: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clk.Advance(dialTimeout)
+	if err := <-errs; !errors.Is(err, ErrDialTimeout) {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package irc
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+)
+
+// WithLogger is an Option for specifying a logger for dial attempts, TLS
+// handshakes, and at debug level every line read and sent.
+type WithLogger struct{ *slog.Logger }
+
+func (l WithLogger) apply(t *transport) {
+	t.logger = l.Logger
+}
+
+// logTLS logs the result of a TLS handshake.
+func (t *transport) logTLS(addr string, state tls.ConnectionState) {
+	if t.logger == nil {
+		return
+	}
+	t.logger.Info("TLS handshake complete",
+		"addr", addr,
+		"version", tls.VersionName(state.Version),
+		"cipher_suite", tls.CipherSuiteName(state.CipherSuite),
+		"server_name", state.ServerName,
+		"resumed", state.DidResume)
+}
+
+func (t *transport) logErr(msg string, err error) {
+	if t.logger != nil {
+		t.logger.Warn(msg, "err", err)
+	}
+}
+
+// trace wraps c to log its lines, if a logger is set.
+func (t *transport) trace(c Conn) Conn {
+	if t.logger == nil {
+		return c
+	}
+	return tracingConn{Conn: c, logger: t.logger}
+}
+
+type tracingConn struct {
+	Conn
+	logger *slog.Logger
+}
+
+func (c tracingConn) Read() (*Message, error) {
+	msg, err := c.Conn.Read()
+	if msg != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
+		c.logger.Debug("recv", "line", RedactLine(msg.Raw()))
+	}
+	return msg, err
+}
+
+func (c tracingConn) Send(line string) error {
+	err := c.Conn.Send(line)
+	if c.logger.Enabled(context.Background(), slog.LevelDebug) {
+		c.logger.Debug("send", "line", RedactLine(line), "err", err)
+	}
+	return err
+}
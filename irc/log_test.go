@@ -0,0 +1,39 @@
+package irc
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type nopConn struct{ sent []string }
+
+func (c *nopConn) Read() (*Message, error) {
+	return ParseMessage(":tmi.twitch.tv 001 justinfan1 :Welcome"), nil
+}
+func (c *nopConn) Send(s string) error { c.sent = append(c.sent, s); return nil }
+func (c *nopConn) Close() error        { return nil }
+
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	tr := transport{logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	c := &nopConn{}
+	conn := tr.trace(c)
+	conn.Send("PASS oauth:secret")
+	conn.Send("NICK someone")
+	conn.Read()
+
+	if c.sent[0] != "PASS oauth:secret" {
+		t.Errorf("Wrong line sent: %q", c.sent[0])
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret") {
+		t.Errorf("Password logged: %s", out)
+	}
+	for _, want := range []string{`line="PASS [REDACTED]"`, `line="NICK someone"`, "msg=recv"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Missing %s in %s", want, out)
+		}
+	}
+}
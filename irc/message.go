@@ -1,7 +1,9 @@
 package irc
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -35,6 +37,7 @@ func (m *Message) Arg(i int) string {
 	return ""
 }
 
+// Raw returns the line the message was parsed from. Use RedactLine to log it.
 func (m *Message) Raw() string {
 	return m.raw
 }
@@ -56,11 +59,73 @@ func parseTags(s string) map[string]string {
 	return t
 }
 
+var escapeTag = strings.NewReplacer(";", "\\:", " ", "\\s", "\r", "\\r", "\n", "\\n", "\\", "\\\\")
+
+// FormatTags encodes tags for use in a message line, without the leading '@'.
+// Keys are sorted for a stable result. Client-only tags are prefixed with
+// '+', e.g. +client-nonce.
+func FormatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(';')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		escapeTag.WriteString(&sb, tags[k])
+	}
+	return sb.String()
+}
+
+// Encode returns the message as a line, the inverse of ParseMessage. The final
+// argument is sent as trailer if HasTrailer is set or if it requires it.
+func (m *Message) Encode() string {
+	var sb strings.Builder
+	if len(m.Tags) > 0 {
+		sb.WriteByte('@')
+		sb.WriteString(FormatTags(m.Tags))
+		sb.WriteByte(' ')
+	}
+	if m.Source != "" {
+		sb.WriteByte(':')
+		sb.WriteString(m.Source)
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(m.Command)
+	for i, arg := range m.Args {
+		sb.WriteByte(' ')
+		if i == len(m.Args)-1 && (m.HasTrailer || arg == "" || arg[0] == ':' || strings.IndexByte(arg, ' ') >= 0) {
+			sb.WriteByte(':')
+		}
+		sb.WriteString(arg)
+	}
+	return sb.String()
+}
+
 func (m *Message) String() string {
+	var args interface{} = m.Args
+	if credential(m.Command, strings.Join(m.Args, " ")) {
+		args = Redacted
+	}
 	if len(m.Tags) > 0 {
-		return fmt.Sprintf("Message(tags=%v, from=%s, %s, args=%v)", m.Tags, m.Source, m.Command, m.Args)
+		return fmt.Sprintf("Message(tags=%v, from=%s, %s, args=%v)", m.Tags, m.Source, m.Command, args)
+	}
+	return fmt.Sprintf("Message(from=%s, %s, args=%v)", m.Source, m.Command, args)
+}
+
+// MarshalJSON hides the arguments of credential commands like String.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	if credential(m.Command, strings.Join(m.Args, " ")) {
+		m.Args = []string{Redacted}
 	}
-	return fmt.Sprintf("Message(from=%s, %s, args=%v)", m.Source, m.Command, m.Args)
+	return json.Marshal(message(m))
 }
 
 func ParseMessage(line string) *Message {
@@ -44,3 +44,27 @@ func BenchmarkParser(b *testing.B) {
 	}
 	result = r
 }
+
+func TestEncode(t *testing.T) {
+	m := &Message{
+		Tags:       map[string]string{"reply-parent-msg-id": "abc", "+draft/x": "a; b\\c\r\n", "+flag": ""},
+		Command:    "PRIVMSG",
+		Args:       []string{"#channel", "Kappa"},
+		HasTrailer: true,
+	}
+	line := m.Encode()
+	if line != "@+draft/x=a\\:\\sb\\\\c\\r\\n;+flag=;reply-parent-msg-id=abc PRIVMSG #channel :Kappa" {
+		t.Errorf("Wrong line: %q", line)
+	}
+
+	p := ParseMessage(line)
+	if p.Tags["+draft/x"] != m.Tags["+draft/x"] || p.Tags["reply-parent-msg-id"] != "abc" || p.Trailer(1) != "Kappa" {
+		t.Errorf("Wrong round trip: %#v", p)
+	}
+
+	for _, s := range benchMessages {
+		if e := ParseMessage(s).Encode(); e != s {
+			t.Errorf("Wrong round trip: %q != %q", e, s)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package irc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"raccatta.cc/tmi/clock"
+)
+
+// An Option configures a Dialer created by New or NewTLS.
+type Option interface {
+	apply(*transport)
+}
+
+// TLSOpt is the former name of Option.
+type TLSOpt = Option
+
+// transport holds the options shared by all Dialers.
+type transport struct {
+	config *tls.Config
+	clk    clock.Clock
+	logger *slog.Logger
+}
+
+func (t *transport) clock() clock.Clock {
+	if t.clk == nil {
+		return clock.System
+	}
+	return t.clk
+}
+
+// dialContext returns a context canceled after dialTimeout on the clock.
+func (t *transport) dialContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	expired := t.clock().After(dialTimeout)
+	go func() {
+		select {
+		case <-expired:
+			cancel(ErrDialTimeout)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(nil) }
+}
+
+// dialError reports a dial canceled by dialContext as ErrDialTimeout.
+func dialError(ctx context.Context, err error) error {
+	if errors.Is(context.Cause(ctx), ErrDialTimeout) {
+		return fmt.Errorf("%w: %v", ErrDialTimeout, err)
+	}
+	return err
+}
+
+// TLSConfig is an Option for specifying a tls.Config.
+type TLSConfig struct{ *tls.Config }
+
+func (cfg TLSConfig) apply(t *transport) {
+	t.config = cfg.Config
+}
+
+// WithClock is an Option for specifying the clock the dial timeout is measured
+// with. It does not apply to read and write deadlines, which the network
+// enforces against the system time.
+type WithClock struct{ clock.Clock }
+
+func (c WithClock) apply(t *transport) {
+	t.clk = c.Clock
+}
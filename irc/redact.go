@@ -0,0 +1,97 @@
+package irc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Redacted replaces credentials in lines, logs and errors.
+const Redacted = "[REDACTED]"
+
+// A Secret is a credential such as an OAuth token. It never prints its value,
+// also not with fmt verbs or as JSON; convert it to a string to use it.
+type Secret string
+
+func (s Secret) String() string   { return Redacted }
+func (s Secret) GoString() string { return `"` + Redacted + `"` }
+
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		f.Write([]byte(s.GoString()))
+		return
+	}
+	f.Write([]byte(Redacted))
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + Redacted + `"`), nil
+}
+
+// mechanisms are the SASL mechanism names, which AUTHENTICATE carries before
+// the credentials.
+var mechanisms = map[string]bool{
+	"PLAIN":                    true,
+	"EXTERNAL":                 true,
+	"SCRAM-SHA-1":              true,
+	"SCRAM-SHA-256":            true,
+	"SCRAM-SHA-512":            true,
+	"ECDSA-NIST256P-CHALLENGE": true,
+}
+
+// credential reports whether the arguments of a command are credentials. The
+// SASL AUTHENTICATE carries them, except for the "+" and "*" markers and the
+// mechanism name.
+func credential(command, args string) bool {
+	switch strings.ToUpper(command) {
+	case "PASS":
+		return true
+	case "AUTHENTICATE":
+		return args != "+" && args != "*" && !mechanisms[strings.ToUpper(args)]
+	}
+	return false
+}
+
+// RedactLine hides the credentials of a line such as PASS or AUTHENTICATE, so
+// lines can be logged, traced or recorded.
+func RedactLine(line string) string {
+	rest := line
+	if strings.HasPrefix(rest, "@") {
+		_, rest = splitb(rest, ' ')
+	}
+	if strings.HasPrefix(rest, ":") {
+		_, rest = splitb(rest, ' ')
+	}
+	command, args := splitb(rest, ' ')
+	if !credential(command, args) || args == "" {
+		return line
+	}
+	return line[:len(line)-len(args)] + Redacted
+}
+
+// RedactError hides the given secrets in the message of err. The error chain
+// is kept for errors.Is and errors.As.
+func RedactError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+	return redactedError{err: err, secrets: secrets}
+}
+
+type redactedError struct {
+	err     error
+	secrets []string
+}
+
+func (e redactedError) Error() string {
+	s := e.err.Error()
+	for _, secret := range e.secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
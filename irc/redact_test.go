@@ -0,0 +1,85 @@
+package irc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	const token = "oauth:s3cr3t"
+	v := struct {
+		Nick   string
+		Passwd Secret
+	}{"someone", token}
+
+	outputs := []string{
+		v.Passwd.String(),
+		fmt.Sprint(v.Passwd),
+		fmt.Sprintf("%v %+v %#v %s %q %x %d", v, v, v, v.Passwd, v.Passwd, v.Passwd, v.Passwd),
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs = append(outputs, string(b))
+	for _, s := range outputs {
+		if strings.Contains(s, "s3cr3t") || !strings.Contains(s, Redacted) {
+			t.Errorf("Secret not redacted: %s", s)
+		}
+	}
+	if string(v.Passwd) != token {
+		t.Errorf("Wrong value: %q", string(v.Passwd))
+	}
+}
+
+func TestRedactLine(t *testing.T) {
+	for line, want := range map[string]string{
+		"PASS oauth:abc":             "PASS [REDACTED]",
+		"pass oauth:abc":             "pass [REDACTED]",
+		"@a=b :x PASS :oauth:abc":    "@a=b :x PASS [REDACTED]",
+		"PASS":                       "PASS",
+		"PRIVMSG #chan :PASS x":      "PRIVMSG #chan :PASS x",
+		"PASSWORD reset is a scam!":  "PASSWORD reset is a scam!",
+		"AUTHENTICATE PLAIN":         "AUTHENTICATE PLAIN",
+		"AUTHENTICATE SCRAM-SHA-256": "AUTHENTICATE SCRAM-SHA-256",
+		"AUTHENTICATE +":             "AUTHENTICATE +",
+		"AUTHENTICATE *":             "AUTHENTICATE *",
+		"AUTHENTICATE AHNvbWVvbmU=":  "AUTHENTICATE [REDACTED]",
+	} {
+		if got := RedactLine(line); got != want {
+			t.Errorf("RedactLine(%q) = %q, want %q", line, got, want)
+		}
+	}
+
+	msg := ParseMessage("PASS oauth:abc")
+	b, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, _ := json.Marshal(*msg)
+	for _, s := range []string{msg.String(), string(b), string(b2)} {
+		if strings.Contains(s, "abc") || !strings.Contains(s, Redacted) {
+			t.Errorf("Message not redacted: %s", s)
+		}
+	}
+	if b, _ := json.Marshal(ParseMessage("PRIVMSG #chan :hi")); string(b) != `{"tags":null,"source":"","command":"PRIVMSG","args":["#chan","hi"],"trailer":true}` {
+		t.Errorf("Wrong JSON: %s", b)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	err := RedactError(fmt.Errorf("write \"PASS oauth:abc\": %w", io.ErrClosedPipe), "oauth:abc")
+	if s := err.Error(); s != `write "PASS [REDACTED]": io: read/write on closed pipe` {
+		t.Errorf("Wrong error: %s", s)
+	}
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Chain lost: %v", err)
+	}
+	if RedactError(nil, "x") != nil {
+		t.Error("nil error not kept")
+	}
+}
@@ -3,51 +3,46 @@ package irc
 import (
 	"context"
 	"crypto/tls"
-	"time"
 )
 
-type TLSOpt interface {
-	apply(*tlsTransport)
-}
-
-// TLSConfig is a TLSOpt for specifying a tls.Config.
-type TLSConfig struct{ *tls.Config }
-
-func (cfg TLSConfig) apply(t *tlsTransport) {
-	t.config = cfg.Config
-}
-
 // NewTLS is an extended initializer for TLS-based IRC.
 func NewTLS(addr string, options ...TLSOpt) Dialer {
-	transport := tlsTransport{
+	t := tlsTransport{
 		addr: defaultPort(addr, "6697"),
 	}
 	for _, opt := range options {
-		opt.apply(&transport)
+		opt.apply(&t.transport)
 	}
-	return transport
+	return t
 }
 
 type tlsTransport struct {
-	addr   string
-	dialer *tls.Dialer
-	config *tls.Config
+	addr string
+	transport
 }
 
 func (wt tlsTransport) Dial(ctx context.Context) (Conn, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	ctx, cancel := wt.dialContext(ctx)
 	defer cancel()
+	if wt.logger != nil {
+		wt.logger.Info("Dialing", "addr", wt.addr)
+	}
 	config := wt.config
 	nc, err := (&tls.Dialer{
 		Config: config,
 	}).DialContext(ctx, "tcp", wt.addr)
 	if err != nil {
+		err = dialError(ctx, err)
+		wt.logErr("Dial failed", err)
 		return nil, err
 	}
 	c := nc.(*tls.Conn)
 	if err := c.HandshakeContext(ctx); err != nil {
+		err = dialError(ctx, err)
+		wt.logErr("TLS handshake failed", err)
 		nc.Close()
 		return nil, err
 	}
-	return &netConn{conn: c}, nil
+	wt.logTLS(wt.addr, c.ConnectionState())
+	return wt.trace(&netConn{conn: c}), nil
 }
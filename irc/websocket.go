@@ -2,6 +2,8 @@ package irc
 
 import (
 	"context"
+	"crypto/tls"
+	"io"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -10,16 +12,25 @@ import (
 type websocketTransport struct {
 	addr   string
 	dialer *websocket.Dialer
+	transport
 }
 
 func (wt websocketTransport) Dial(ctx context.Context) (Conn, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	ctx, cancel := wt.dialContext(ctx)
 	defer cancel()
+	if wt.logger != nil {
+		wt.logger.Info("Dialing", "addr", wt.addr)
+	}
 	c, _, err := wt.dialer.DialContext(ctx, wt.addr, nil)
 	if err != nil {
+		err = dialError(ctx, err)
+		wt.logErr("Dial failed", err)
 		return nil, err
 	}
-	return &websocketConn{conn: c}, nil
+	if tc, ok := c.UnderlyingConn().(*tls.Conn); ok {
+		wt.logTLS(wt.addr, tc.ConnectionState())
+	}
+	return wt.trace(&websocketConn{conn: c}), nil
 }
 
 type websocketConn struct {
@@ -41,7 +52,10 @@ func (wc *websocketConn) Read() (*Message, error) {
 			wc.conn.SetReadDeadline(time.Now().Add(deadline))
 		}
 		_, message, err := wc.conn.ReadMessage()
-		if err != nil {
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			// Report an orderly close like a net.Conn does
+			return nil, io.EOF
+		} else if err != nil {
 			return nil, err
 		}
 
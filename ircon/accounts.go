@@ -0,0 +1,223 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// DefaultSendLimit and DefaultSendWindow follow TMI's PRIVMSG rate limit for
+// regular accounts. Moderators and verified bots may raise these.
+const (
+	DefaultSendLimit  = 20
+	DefaultSendWindow = 30 * time.Second
+)
+
+var (
+	ErrUnknownAccount = errors.New("Unknown account")
+	ErrAccountExists  = errors.New("Account already exists")
+)
+
+// An AccountHandler receives events from all accounts of a Registry. Calls
+// identify the account they originate from.
+type AccountHandler interface {
+	Connected(a *Account)
+	Disconnected(a *Account, err error)
+	Message(a *Account, msg *irc.Message)
+}
+
+// An Account is a bot identity with its own connection and channels.
+type Account struct {
+	Name string
+	*IRCon
+
+	// SendLimit PRIVMSGs are sent per SendWindow by Say. If zero,
+	// DefaultSendLimit and DefaultSendWindow are used. These, and the Clock of
+	// the IRCon, should be set before the first Say or Join.
+	SendLimit  int
+	SendWindow time.Duration
+
+	mu       sync.Mutex
+	channels map[string]bool
+	sends    *limiter
+	joins    *limiter
+	cancel   context.CancelFunc
+}
+
+// Join joins channels, now and after every reconnect. It blocks as needed to
+// respect the JOIN rate limit.
+func (a *Account) Join(channels ...string) {
+	a.mu.Lock()
+	for _, ch := range channels {
+		a.channels[normalizeChannel(ch)] = true
+	}
+	a.mu.Unlock()
+	a.join(context.Background(), channels)
+}
+
+func (a *Account) join(ctx context.Context, channels []string) {
+	a.mu.Lock()
+	if a.joins == nil {
+		a.joins = newLimiter(DefaultJoinLimit, DefaultJoinWindow, a.clock())
+	}
+	a.mu.Unlock()
+	for _, ch := range channels {
+		if err := a.joins.Wait(ctx); err != nil {
+			return
+		}
+		a.Send("JOIN #" + normalizeChannel(ch))
+	}
+}
+
+// Part leaves channels.
+func (a *Account) Part(channels ...string) {
+	a.mu.Lock()
+	for _, ch := range channels {
+		delete(a.channels, normalizeChannel(ch))
+	}
+	a.mu.Unlock()
+	for _, ch := range channels {
+		a.Send("PART #" + normalizeChannel(ch))
+	}
+}
+
+// Channels returns the channels of the account.
+func (a *Account) Channels() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	res := make([]string, 0, len(a.channels))
+	for ch := range a.channels {
+		res = append(res, ch)
+	}
+	return res
+}
+
+// Say sends a message to a channel, paced to the account's rate limit.
+func (a *Account) Say(ctx context.Context, channel, text string) error {
+	a.mu.Lock()
+	if a.sends == nil {
+		limit, window := a.SendLimit, a.SendWindow
+		if limit <= 0 || window <= 0 {
+			limit, window = DefaultSendLimit, DefaultSendWindow
+		}
+		a.sends = newLimiter(limit, window, a.clock())
+	}
+	sends := a.sends
+	a.mu.Unlock()
+	if err := sends.Wait(ctx); err != nil {
+		return err
+	}
+	return a.IRCon.Say(ctx, channel, text)
+}
+
+// A Registry manages the connections of multiple accounts.
+type Registry struct {
+	dialer irc.Dialer
+
+	mu       sync.Mutex
+	accounts map[string]*Account
+	ctx      context.Context
+	h        AccountHandler
+	wg       sync.WaitGroup
+}
+
+// NewRegistry creates a Registry connecting all accounts through d.
+func NewRegistry(d irc.Dialer) *Registry {
+	return &Registry{
+		dialer:   d,
+		accounts: make(map[string]*Account),
+	}
+}
+
+// Add registers an account with its credentials. If the registry is running,
+// the account connects immediately.
+func (r *Registry) Add(name, passwd string) (*Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.accounts[name] != nil {
+		return nil, ErrAccountExists
+	}
+	a := &Account{
+		Name:     name,
+		IRCon:    New(r.dialer, TwitchHandshaker(name, passwd)),
+		channels: make(map[string]bool),
+	}
+	r.accounts[name] = a
+	if r.ctx != nil {
+		r.start(a)
+	}
+	return a, nil
+}
+
+// Remove disconnects and forgets an account.
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	a := r.accounts[name]
+	delete(r.accounts, name)
+	r.mu.Unlock()
+	if a != nil && a.cancel != nil {
+		a.cancel()
+	}
+}
+
+// Get returns an account, or nil if it is not registered.
+func (r *Registry) Get(name string) *Account {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.accounts[name]
+}
+
+// Say sends a message to a channel through the given account.
+func (r *Registry) Say(ctx context.Context, account, channel, text string) error {
+	a := r.Get(account)
+	if a == nil {
+		return ErrUnknownAccount
+	}
+	return a.Say(ctx, channel, text)
+}
+
+// Run maintains the connections of all accounts until the context is done.
+func (r *Registry) Run(ctx context.Context, h AccountHandler) {
+	r.mu.Lock()
+	r.ctx, r.h = ctx, h
+	for _, a := range r.accounts {
+		r.start(a)
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	r.wg.Wait()
+}
+
+// start runs an account's connection. The lock must be held.
+func (r *Registry) start(a *Account) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	a.cancel = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		a.Run(ctx, &accountHandler{ctx: ctx, a: a, h: r.h})
+	}()
+}
+
+type accountHandler struct {
+	ctx context.Context
+	a   *Account
+	h   AccountHandler
+}
+
+func (ah *accountHandler) Connected() {
+	go ah.a.join(ah.ctx, ah.a.Channels())
+	ah.h.Connected(ah.a)
+}
+
+func (ah *accountHandler) Disconnected(err error) {
+	ah.h.Disconnected(ah.a, err)
+}
+
+func (ah *accountHandler) Message(msg *irc.Message) {
+	ah.h.Message(ah.a, msg)
+}
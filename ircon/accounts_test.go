@@ -0,0 +1,127 @@
+package ircon
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+)
+
+type accountEvent struct {
+	a   *Account
+	msg *irc.Message
+}
+
+// accountRecorder is an AccountHandler collecting events.
+type accountRecorder struct {
+	connected chan *Account
+	messages  chan accountEvent
+}
+
+func (r *accountRecorder) Connected(a *Account)                 { r.connected <- a }
+func (r *accountRecorder) Disconnected(*Account, error)         {}
+func (r *accountRecorder) Message(a *Account, msg *irc.Message) { r.messages <- accountEvent{a, msg} }
+
+func TestRegistry(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	d := fakeDialer{conns: make(chan *fakeConn, 4)}
+	r := NewRegistry(d)
+	a, err := r.Add("a", "oauth:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Clock = clk
+	a.Reconnect = Fixed{}
+	a.SendLimit, a.SendWindow = 1, time.Second
+	if _, err := r.Add("a", "oauth:a"); err != ErrAccountExists {
+		t.Errorf("Wrong error: %v", err)
+	}
+	b, _ := r.Add("b", "oauth:b")
+	if r.Get("a") != a || r.Get("b") != b || r.Get("c") != nil {
+		t.Error("Wrong accounts")
+	}
+	if err := r.Say(context.Background(), "c", "#x", "hi"); err != ErrUnknownAccount {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	rec := &accountRecorder{
+		connected: make(chan *Account, 4),
+		messages:  make(chan accountEvent, 16),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ran := make(chan struct{})
+	go func() {
+		r.Run(ctx, rec)
+		close(ran)
+	}()
+
+	// Accounts connect in any order
+	conns := make(map[string]*fakeConn)
+	for n := 0; n < 2; n++ {
+		c := newFakeConn()
+		d.conns <- c
+		nick := strings.TrimPrefix(c.expect("NICK "), "NICK ")
+		conns[nick] = c
+		c.in <- ":tmi.twitch.tv 001 " + nick + " :Welcome, GLHF!"
+		<-rec.connected
+	}
+	if conns["a"] == nil || conns["b"] == nil {
+		t.Fatalf("Wrong connections: %v", conns)
+	}
+
+	a.Join("#Chan")
+	conns["a"].expect("JOIN #chan")
+	conns["a"].in <- ":x!x@x.tmi.twitch.tv PRIVMSG #chan :hello"
+	for e := range rec.messages {
+		if e.msg.Command != "PRIVMSG" {
+			continue
+		}
+		if e.a != a {
+			t.Errorf("Routed to %s", e.a.Name)
+		}
+		break
+	}
+
+	// Say is paced to the account's SendLimit
+	if err := r.Say(ctx, "a", "#chan", "one"); err != nil {
+		t.Fatal(err)
+	}
+	conns["a"].expect("PRIVMSG #chan :one")
+	waiters := clk.Waiters()
+	said := make(chan error, 1)
+	go func() { said <- r.Say(ctx, "a", "#chan", "two") }()
+	for clk.Waiters() == waiters {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case line := <-conns["a"].out:
+		t.Fatalf("Limit not applied: %q", line)
+	default:
+	}
+	clk.Advance(time.Second)
+	conns["a"].expect("PRIVMSG #chan :two")
+	if err := <-said; err != nil {
+		t.Fatal(err)
+	}
+
+	// Channels are rejoined after a reconnect
+	again := newFakeConn()
+	d.conns <- again
+	conns["a"].Close()
+	again.expect("NICK a")
+	again.in <- ":tmi.twitch.tv 001 a :Welcome, GLHF!"
+	<-rec.connected
+	again.expect("JOIN #chan")
+
+	r.Remove("b")
+	<-conns["b"].done
+	if r.Get("b") != nil {
+		t.Error("Account not removed")
+	}
+	cancel()
+	<-ran
+}
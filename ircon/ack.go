@@ -0,0 +1,197 @@
+package ircon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+// DefaultAckTimeout is the time SendAck waits for an acknowledgement.
+const DefaultAckTimeout = 10 * time.Second
+
+var ErrAckTimeout = errors.New("Message was not acknowledged")
+
+// A Pending is a message sent with SendAck, awaiting acknowledgement.
+type Pending struct {
+	Nonce   string
+	Channel string
+
+	done  chan struct{}
+	state *twitch.UserState
+	err   error
+	sent  bool // Written to the connection, guarded by acks.mu
+}
+
+// Done is closed when the message is acknowledged or failed.
+func (p *Pending) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait waits for the message to be acknowledged. On success it returns the
+// USERSTATE echoing the message, which holds the message id. A rejection by
+// TMI results in a *twitch.NoticeError.
+func (p *Pending) Wait(ctx context.Context) (*twitch.UserState, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return p.state, p.err
+	}
+}
+
+// joinFailures are the NOTICE msg-ids TMI answers a failed JOIN with, but also
+// a PRIVMSG to a channel that cannot be joined.
+var joinFailures = map[string]bool{
+	"msg_channel_suspended": true,
+	"tos_ban":               true,
+}
+
+// acks matches incoming USERSTATE and NOTICE messages to pending messages.
+// TMI echoes the client-nonce in USERSTATE, but NOTICEs carry no reference, so
+// these are attributed to the oldest pending message in the channel that was
+// written to the connection. Only NOTICEs from the catalog of rejections are
+// attributed, except those answering a pending JOIN.
+type acks struct {
+	mu      sync.Mutex
+	pending map[string][]*Pending
+
+	// joining reports whether a JOIN to a channel is pending, if set
+	joining func(channel string) bool
+}
+
+// sent marks p as written to the connection.
+func (a *acks) sent(p *Pending) {
+	a.mu.Lock()
+	p.sent = true
+	a.mu.Unlock()
+}
+
+func (a *acks) add(p *Pending) {
+	a.mu.Lock()
+	if a.pending == nil {
+		a.pending = make(map[string][]*Pending)
+	}
+	a.pending[p.Channel] = append(a.pending[p.Channel], p)
+	a.mu.Unlock()
+}
+
+// resolve completes p, if it is still pending.
+func (a *acks) resolve(p *Pending, state *twitch.UserState, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	list := a.pending[p.Channel]
+	for n, v := range list {
+		if v == p {
+			a.pending[p.Channel] = append(list[:n:n], list[n+1:]...)
+			p.state, p.err = state, err
+			close(p.done)
+			return
+		}
+	}
+}
+
+func (a *acks) Message(msg *irc.Message) {
+	var (
+		p     *Pending
+		state *twitch.UserState
+		err   error
+	)
+	a.mu.Lock()
+	switch msg.Command {
+	case "USERSTATE":
+		state = twitch.ParseUserState(msg)
+		if state.ClientNonce == "" {
+			break
+		}
+		for _, v := range a.pending[state.Channel] {
+			if v.Nonce == state.ClientNonce {
+				p = v
+				break
+			}
+		}
+	case "NOTICE":
+		n := twitch.ParseNotice(msg)
+		if !n.Known() || (joinFailures[n.MsgID] && a.joining != nil && a.joining(n.Channel)) {
+			break
+		}
+		for _, v := range a.pending[n.Channel] {
+			if v.sent {
+				p, err = v, n.Err()
+				break
+			}
+		}
+	}
+	a.mu.Unlock()
+	if p != nil {
+		a.resolve(p, state, err)
+	}
+}
+
+// reset fails all pending messages.
+func (a *acks) reset(err error) {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+	for _, list := range pending {
+		for _, p := range list {
+			p.err = err
+			close(p.done)
+		}
+	}
+}
+
+func newNonce() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// SendAck sends a PRIVMSG with a generated client-nonce, and returns a Pending
+// that is resolved when TMI acknowledges or rejects the message, or after
+// AckTimeout.
+//
+// TMI echoes the nonce when accepting a message, but a rejecting NOTICE does
+// not refer to the message. A rejection from the twitch NOTICE catalog, e.g.
+// msg_ratelimit, fails the oldest message in its channel that was already
+// written to the connection. Concurrent SendAcks to the same channel may
+// therefore see each other's rejections.
+func (i *IRCon) SendAck(ctx context.Context, msg *irc.Message) (*Pending, error) {
+	p := &Pending{
+		Nonce:   newNonce(),
+		Channel: normalizeChannel(msg.Arg(0)),
+		done:    make(chan struct{}),
+	}
+	tags := map[string]string{"client-nonce": p.Nonce}
+	for k, v := range msg.Tags {
+		tags[k] = v
+	}
+	m := *msg
+	m.Tags = tags
+
+	i.acks.add(p)
+	if err := i.sendContext(ctx, m.Encode(), func() { i.acks.sent(p) }); err != nil {
+		i.acks.resolve(p, nil, err)
+		return nil, err
+	}
+
+	timeout := i.AckTimeout
+	if timeout == 0 {
+		timeout = DefaultAckTimeout
+	}
+	expired := i.clock().After(timeout)
+	go func() {
+		select {
+		case <-p.done:
+		case <-expired:
+			i.acks.resolve(p, nil, ErrAckTimeout)
+		}
+	}()
+	return p, nil
+}
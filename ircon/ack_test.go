@@ -0,0 +1,96 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+func TestAcks(t *testing.T) {
+	a := acks{joining: func(ch string) bool { return ch == "joining" }}
+	ok := &Pending{Nonce: "n1", Channel: "chan", done: make(chan struct{})}
+	fail := &Pending{Nonce: "n2", Channel: "chan", done: make(chan struct{})}
+	waiting := &Pending{Nonce: "n3", Channel: "chan", done: make(chan struct{})}
+	joining := &Pending{Nonce: "n4", Channel: "joining", done: make(chan struct{})}
+	suspended := &Pending{Nonce: "n5", Channel: "suspended", done: make(chan struct{})}
+	a.add(waiting)
+	for _, p := range []*Pending{ok, fail, joining, suspended} {
+		a.add(p)
+		a.sent(p)
+	}
+
+	a.Message(irc.ParseMessage("@client-nonce=n1;id=abc :tmi.twitch.tv USERSTATE #chan"))
+	// NOTICEs answering a pending JOIN or unknown notices are not attributed
+	a.Message(irc.ParseMessage("@msg-id=msg_channel_suspended :tmi.twitch.tv NOTICE #joining :This channel does not exist or has been suspended."))
+	a.Message(irc.ParseMessage("@msg-id=msg_channel_suspended :tmi.twitch.tv NOTICE #suspended :This channel does not exist or has been suspended."))
+	a.Message(irc.ParseMessage("@msg-id=host_on :tmi.twitch.tv NOTICE #chan :Now hosting someone."))
+	a.Message(irc.ParseMessage("@msg-id=msg_duplicate :tmi.twitch.tv NOTICE #chan :Your message was not sent because it is identical to the previous one you sent, less than 30 seconds ago."))
+
+	ctx := context.Background()
+	if s, err := ok.Wait(ctx); err != nil || s.ID != "abc" {
+		t.Errorf("Wrong ack: %v, %v", s, err)
+	}
+	var nerr *twitch.NoticeError
+	if _, err := fail.Wait(ctx); !errors.As(err, &nerr) || nerr.MsgID != "msg_duplicate" {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if _, err := suspended.Wait(ctx); !errors.As(err, &nerr) || nerr.MsgID != "msg_channel_suspended" {
+		t.Errorf("Wrong error: %v", err)
+	}
+	for _, p := range []*Pending{waiting, joining} {
+		select {
+		case <-p.Done():
+			t.Errorf("Message %s resolved: %v", p.Nonce, p.err)
+		default:
+		}
+	}
+}
+
+func TestSendAck(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	i := New(d, TwitchHandshaker("", ""))
+	i.Clock = clk
+	i.AckTimeout = 5 * time.Second
+	r := newRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go i.Run(ctx, r)
+	<-r.connected
+
+	// Channels are matched case-insensitively
+	msg := &irc.Message{Command: "PRIVMSG", Args: []string{"#Chan", "hi"}, HasTrailer: true}
+	acked, err := i.SendAck(ctx, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := c.expect("@client-nonce=")
+	if want := "@client-nonce=" + acked.Nonce + " PRIVMSG #Chan :hi"; line != want {
+		t.Errorf("Wrong line: %q", line)
+	}
+	c.in <- "@client-nonce=" + acked.Nonce + ";id=abc :tmi.twitch.tv USERSTATE #chan"
+	if s, err := acked.Wait(ctx); err != nil || s.ID != "abc" {
+		t.Errorf("Wrong ack: %v, %v", s, err)
+	}
+
+	lost, err := i.SendAck(ctx, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first SendAck's timer is still pending on the fake clock
+	for clk.Waiters() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(5 * time.Second)
+	if _, err := lost.Wait(ctx); err != ErrAckTimeout {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package ircon
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// ConnLoad describes a Pool connection to a Balancer.
+type ConnLoad struct {
+	ID       int
+	Healthy  bool
+	Channels int
+}
+
+// A Balancer distributes channels across the connections of a Pool.
+type Balancer interface {
+	// Pick returns the ID of the connection to join channel on, or -1 if no
+	// connection can take it.
+	Pick(channel string, conns []ConnLoad) int
+}
+
+// LeastLoaded assigns channels to the healthy connection with the fewest
+// channels. If Max is set, connections take at most Max channels.
+type LeastLoaded struct {
+	Max int
+}
+
+func (b LeastLoaded) Pick(channel string, conns []ConnLoad) int {
+	best := -1
+	for n, c := range conns {
+		if !c.Healthy || (b.Max > 0 && c.Channels >= b.Max) {
+			continue
+		}
+		if best < 0 || c.Channels < conns[best].Channels {
+			best = n
+		}
+	}
+	if best < 0 {
+		return -1
+	}
+	return conns[best].ID
+}
+
+// ConsistentHash assigns channels by hashing their name onto a ring of
+// connections, so assignments are stable as connections fail and recover.
+type ConsistentHash struct {
+	// Replicas is the number of points per connection on the ring. If zero, a
+	// default of 64 is used.
+	Replicas int
+
+	size int
+	ring []ringPoint
+}
+
+type ringPoint struct {
+	hash uint32
+	id   int
+}
+
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func (b *ConsistentHash) Pick(channel string, conns []ConnLoad) int {
+	if len(conns) != b.size {
+		b.build(conns)
+	}
+	if len(b.ring) == 0 {
+		return -1
+	}
+	healthy := make(map[int]bool, len(conns))
+	for _, c := range conns {
+		healthy[c.ID] = c.Healthy
+	}
+
+	h := hash(channel)
+	start := sort.Search(len(b.ring), func(i int) bool {
+		return b.ring[i].hash >= h
+	})
+	for i := 0; i < len(b.ring); i++ {
+		p := b.ring[(start+i)%len(b.ring)]
+		if healthy[p.id] {
+			return p.id
+		}
+	}
+	return -1
+}
+
+func (b *ConsistentHash) build(conns []ConnLoad) {
+	replicas := b.Replicas
+	if replicas <= 0 {
+		replicas = 64
+	}
+	b.size = len(conns)
+	b.ring = b.ring[:0]
+	for _, c := range conns {
+		for r := 0; r < replicas; r++ {
+			b.ring = append(b.ring, ringPoint{
+				hash: hash(strconv.Itoa(c.ID) + "-" + strconv.Itoa(r)),
+				id:   c.ID,
+			})
+		}
+	}
+	sort.Slice(b.ring, func(i, j int) bool {
+		return b.ring[i].hash < b.ring[j].hash
+	})
+}
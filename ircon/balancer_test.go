@@ -0,0 +1,35 @@
+package ircon
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestLeastLoaded(t *testing.T) {
+	conns := []ConnLoad{{0, true, 5}, {1, false, 0}, {2, true, 3}}
+	if id := (LeastLoaded{}).Pick("a", conns); id != 2 {
+		t.Errorf("Wrong connection: %d", id)
+	}
+	if id := (LeastLoaded{Max: 3}).Pick("a", conns); id != -1 {
+		t.Errorf("Wrong connection: %d", id)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	conns := []ConnLoad{{0, true, 0}, {1, true, 0}, {2, true, 0}}
+	b := &ConsistentHash{}
+	before := make(map[string]int)
+	for n := 0; n < 100; n++ {
+		ch := "channel" + strconv.Itoa(n)
+		before[ch] = b.Pick(ch, conns)
+	}
+
+	// Only channels of the failed connection move
+	conns[1].Healthy = false
+	for ch, id := range before {
+		after := b.Pick(ch, conns)
+		if after == 1 || (id != 1 && after != id) {
+			t.Errorf("Channel %s moved from %d to %d", ch, id, after)
+		}
+	}
+}
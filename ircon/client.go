@@ -0,0 +1,121 @@
+package ircon
+
+import (
+	"context"
+	"sync"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A Client reads chat through a Pool of anonymous connections, and sends
+// through a separate authenticated IRCon. This keeps reading unaffected by the
+// rate limits and moderation of the sending account.
+//
+// The writer joins the channels as well, so room state and USERSTATE apply to
+// its sends. The handler receives chat from the readers, and everything else,
+// such as USERSTATE and NOTICE, from the writer.
+type Client struct {
+	Reader *Pool
+	Writer *IRCon
+
+	// writer is a single connection Pool around Writer, rejoining and rate
+	// limiting its JOINs.
+	writer *Pool
+}
+
+// NewClient creates a Client with readers anonymous connections, and a writer
+// logged in with the given credentials.
+func NewClient(d irc.Dialer, nick, passwd string, readers int) *Client {
+	w := NewPool(d, TwitchHandshaker(nick, passwd), 1)
+	return &Client{
+		Reader: NewPool(d, AnonymousHandshake{}, readers),
+		Writer: w.conns[0].IRCon,
+		writer: w,
+	}
+}
+
+// Run maintains all connections until the context is done. The handler
+// receives messages from all connections; Connected and Disconnected refer to
+// the writer, as the reader pool recovers on its own. Message is called
+// concurrently from all connections.
+func (c *Client) Run(ctx context.Context, h Handler) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Reader.Run(ctx, clientReader{h})
+	}()
+	if c.writer != nil {
+		c.writer.Run(ctx, clientWriter{h})
+	} else {
+		c.Writer.Run(ctx, h)
+	}
+	wg.Wait()
+}
+
+// Join joins channels for reading and sending.
+func (c *Client) Join(channels ...string) {
+	c.Reader.Join(channels...)
+	if c.writer != nil {
+		c.writer.Join(channels...)
+	}
+}
+
+// Part leaves channels.
+func (c *Client) Part(channels ...string) {
+	c.Reader.Part(channels...)
+	if c.writer != nil {
+		c.writer.Part(channels...)
+	}
+}
+
+// Say sends a message to a channel through the writer.
+func (c *Client) Say(ctx context.Context, channel, text string) error {
+	return c.Writer.Say(ctx, channel, text)
+}
+
+// Reply sends a threaded reply through the writer.
+func (c *Client) Reply(ctx context.Context, parent *irc.Message, text string) error {
+	return c.Writer.Reply(ctx, parent, text)
+}
+
+type clientReader struct {
+	h Handler
+}
+
+func (clientReader) Connected(*PoolConn)           {}
+func (clientReader) Disconnected(*PoolConn, error) {}
+
+func (r clientReader) Message(_ *PoolConn, msg *irc.Message) {
+	if readerCommands[msg.Command] {
+		r.h.Message(msg)
+	}
+}
+
+// readerCommands are the channel messages delivered by the readers. Others,
+// e.g. the USERSTATE of the anonymous nick, are only taken from the writer.
+var readerCommands = map[string]bool{
+	"PRIVMSG":    true,
+	"USERNOTICE": true,
+	"CLEARCHAT":  true,
+	"CLEARMSG":   true,
+	"ROOMSTATE":  true,
+	"HOSTTARGET": true,
+	"JOIN":       true,
+	"PART":       true,
+	"353":        true,
+	"366":        true,
+}
+
+type clientWriter struct {
+	h Handler
+}
+
+func (w clientWriter) Connected(*PoolConn)                 { w.h.Connected() }
+func (w clientWriter) Disconnected(_ *PoolConn, err error) { w.h.Disconnected(err) }
+
+func (w clientWriter) Message(_ *PoolConn, msg *irc.Message) {
+	if !readerCommands[msg.Command] {
+		w.h.Message(msg)
+	}
+}
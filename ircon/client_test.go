@@ -0,0 +1,66 @@
+package ircon
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	rd := fakeDialer{conns: make(chan *fakeConn, 1)}
+	wd := fakeDialer{conns: make(chan *fakeConn, 1)}
+	reader, writer := newFakeConn(), newFakeConn()
+	rd.conns <- reader
+	wd.conns <- writer
+
+	c := NewClient(rd, "someone", "oauth:s3cr3t", 1)
+	c.Writer.dialer = wd
+	rec := newRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, rec)
+	reader.expect("NICK justinfan")
+	writer.expect("NICK someone")
+	writer.in <- ":tmi.twitch.tv 001 someone :Welcome, GLHF!"
+	<-rec.connected
+
+	// Both the readers and the writer join
+	c.Join("#a")
+	reader.expect("JOIN #a")
+	writer.expect("JOIN #a")
+
+	if err := c.Say(ctx, "a", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	writer.expect("PRIVMSG #a :hi")
+	select {
+	case line := <-reader.out:
+		t.Errorf("Sent through reader: %q", line)
+	default:
+	}
+
+	// Chat is delivered once, by the readers, and the rest by the writer
+	writer.in <- ":x!x@x.tmi.twitch.tv PRIVMSG #a :duplicate"
+	writer.in <- "@display-name=someone :tmi.twitch.tv USERSTATE #a"
+	reader.in <- "@display-name=justinfan1 :tmi.twitch.tv USERSTATE #a"
+	reader.in <- ":x!x@x.tmi.twitch.tv PRIVMSG #a :hello"
+	seen := make(map[string]bool)
+	for !seen["USERSTATE"] || !seen["PRIVMSG"] {
+		select {
+		case msg := <-rec.messages:
+			if msg.Command == "PRIVMSG" && msg.Args[1] != "hello" {
+				t.Fatalf("Writer chat delivered: %v", msg.Args)
+			}
+			if msg.Command == "USERSTATE" && msg.Tags["display-name"] != "someone" {
+				t.Fatalf("Reader state delivered: %v", msg.Tags)
+			}
+			seen[msg.Command] = true
+		case <-time.After(time.Second):
+			t.Fatalf("Missing messages: %v", seen)
+		}
+	}
+
+	c.Part("a")
+	reader.expect("PART #a")
+	writer.expect("PART #a")
+}
@@ -0,0 +1,123 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// fakeConn is an irc.Conn fed by the test.
+type fakeConn struct {
+	in   chan string
+	out  chan string
+	once sync.Once
+	done chan struct{}
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		in:   make(chan string, 16),
+		out:  make(chan string, 16),
+		done: make(chan struct{}),
+	}
+}
+
+func (c *fakeConn) Read() (*irc.Message, error) {
+	select {
+	case line := <-c.in:
+		return irc.ParseMessage(line), nil
+	case <-c.done:
+		return nil, io.EOF
+	}
+}
+
+func (c *fakeConn) Send(s string) error {
+	select {
+	case c.out <- s:
+		return nil
+	case <-c.done:
+		return io.ErrClosedPipe
+	}
+}
+
+func (c *fakeConn) Close() error {
+	c.once.Do(func() { close(c.done) })
+	return nil
+}
+
+// expect returns the next sent line with the given prefix, skipping others.
+func (c *fakeConn) expect(prefix string) string {
+	for line := range c.out {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+type fakeDialer struct {
+	conns chan *fakeConn
+}
+
+func (d fakeDialer) Dial(ctx context.Context) (irc.Conn, error) {
+	select {
+	case c := <-d.conns:
+		return c, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// recorder is a Handler collecting events.
+type recorder struct {
+	connected    chan struct{}
+	disconnected chan error
+	messages     chan *irc.Message
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		connected:    make(chan struct{}, 16),
+		disconnected: make(chan error, 16),
+		messages:     make(chan *irc.Message, 16),
+	}
+}
+
+func (r *recorder) Connected()               { r.connected <- struct{}{} }
+func (r *recorder) Disconnected(err error)   { r.disconnected <- err }
+func (r *recorder) Message(msg *irc.Message) { r.messages <- msg }
+
+// overlapConn fails sends that overlap another.
+type overlapConn struct {
+	fakeConn
+	active int32
+}
+
+func (c *overlapConn) Send(s string) error {
+	if atomic.AddInt32(&c.active, 1) > 1 {
+		return errors.New("Concurrent write")
+	}
+	time.Sleep(time.Millisecond)
+	atomic.AddInt32(&c.active, -1)
+	return nil
+}
+
+func TestConnSend(t *testing.T) {
+	c := &conn{Conn: &overlapConn{}}
+	errs := make(chan error, 8)
+	for n := 0; n < cap(errs); n++ {
+		go func() { errs <- c.Send("PING :x") }()
+	}
+	for n := 0; n < cap(errs); n++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package ircon
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// An Overflow policy decides what a Dispatcher does when a queue is full.
+type Overflow int
+
+const (
+	// Block waits for space in the queue, stalling the connection.
+	Block Overflow = iota
+	// DropOldest discards the oldest queued message.
+	DropOldest
+	// DropNewest discards the incoming message.
+	DropNewest
+)
+
+// ByChannel orders messages per channel.
+func ByChannel(msg *irc.Message) string {
+	if ch := msg.Arg(0); strings.HasPrefix(ch, "#") {
+		return ch
+	}
+	return ""
+}
+
+// ByUser orders messages per sending user.
+func ByUser(msg *irc.Message) string {
+	if n := strings.IndexByte(msg.Source, '!'); n >= 0 {
+		return msg.Source[:n]
+	}
+	return msg.Source
+}
+
+// DispatcherStats counts the messages handled by a Dispatcher.
+type DispatcherStats struct {
+	Dispatched uint64
+	Dropped    uint64
+}
+
+// A Dispatcher is a Handler that hands messages to a pool of workers, so a
+// slow Handler does not stall reading from the connection. Messages with the
+// same key are handled in order by the same worker. Connected and
+// Disconnected are passed on directly.
+//
+// IRCon answers PINGs before calling Message, so these are unaffected by the
+// dispatcher.
+type Dispatcher struct {
+	// PanicHandler is called when Message panics on a worker, as IRCon does
+	// for its own callbacks. If nil, the panic is logged to Logger, or the
+	// standard logger if Logger is nil. Both should be set before the first
+	// message.
+	PanicHandler func(*Panic)
+	Logger       *slog.Logger
+
+	h        Handler
+	key      func(*irc.Message) string
+	overflow Overflow
+	workers  []*queue
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	stats DispatcherStats
+}
+
+// NewDispatcher starts workers goroutines with a queue of up to size messages
+// each. Messages are assigned to workers by key; if key is nil, ByChannel is
+// used.
+func NewDispatcher(h Handler, workers, size int, overflow Overflow, key func(*irc.Message) string) *Dispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+	if size <= 0 {
+		size = 1
+	}
+	if key == nil {
+		key = ByChannel
+	}
+	d := &Dispatcher{
+		h:        h,
+		key:      key,
+		overflow: overflow,
+	}
+	for n := 0; n < workers; n++ {
+		q := &queue{size: size}
+		q.cond = sync.NewCond(&q.mu)
+		d.workers = append(d.workers, q)
+		d.wg.Add(1)
+		go d.work(q)
+	}
+	return d
+}
+
+func (d *Dispatcher) Connected() {
+	d.h.Connected()
+}
+
+func (d *Dispatcher) Disconnected(err error) {
+	d.h.Disconnected(err)
+}
+
+func (d *Dispatcher) Message(msg *irc.Message) {
+	h := fnv.New32a()
+	h.Write([]byte(d.key(msg)))
+	q := d.workers[h.Sum32()%uint32(len(d.workers))]
+
+	dropped := q.push(msg, d.overflow)
+	d.mu.Lock()
+	d.stats.Dispatched++
+	if dropped {
+		d.stats.Dropped++
+	}
+	d.mu.Unlock()
+}
+
+// Stats returns the message counters.
+func (d *Dispatcher) Stats() DispatcherStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// Close stops the workers after the queued messages are handled.
+func (d *Dispatcher) Close() {
+	for _, q := range d.workers {
+		q.mu.Lock()
+		q.closed = true
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) work(q *queue) {
+	defer d.wg.Done()
+	for {
+		msg := q.pop()
+		if msg == nil {
+			return
+		}
+		d.handle(msg)
+	}
+}
+
+func (d *Dispatcher) handle(msg *irc.Message) {
+	defer func() {
+		if v := recover(); v != nil {
+			newPanic(v, "Message", msg).report(d.PanicHandler, d.Logger)
+		}
+	}()
+	d.h.Message(msg)
+}
+
+// queue is a bounded FIFO of messages.
+type queue struct {
+	size   int
+	mu     sync.Mutex
+	cond   *sync.Cond
+	msgs   []*irc.Message
+	closed bool
+}
+
+// push adds a message according to the overflow policy, and reports whether a
+// message was dropped.
+func (q *queue) push(msg *irc.Message, overflow Overflow) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	dropped := false
+	for len(q.msgs) >= q.size && !q.closed {
+		switch overflow {
+		case DropNewest:
+			return true
+		case DropOldest:
+			q.msgs = q.msgs[1:]
+			dropped = true
+		default:
+			q.cond.Wait()
+		}
+	}
+	if q.closed {
+		return true
+	}
+	q.msgs = append(q.msgs, msg)
+	q.cond.Broadcast()
+	return dropped
+}
+
+// pop returns the next message, or nil once the queue is closed and empty.
+func (q *queue) pop() *irc.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.msgs) == 0 {
+		if q.closed {
+			return nil
+		}
+		q.cond.Wait()
+	}
+	msg := q.msgs[0]
+	q.msgs = q.msgs[1:]
+	q.cond.Broadcast()
+	return msg
+}
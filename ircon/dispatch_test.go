@@ -0,0 +1,90 @@
+package ircon
+
+import (
+	"strconv"
+	"testing"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestDispatcherOrder(t *testing.T) {
+	r := newRecorder()
+	r.messages = make(chan *irc.Message, 100)
+	d := NewDispatcher(r, 4, 8, Block, nil)
+	for n := 0; n < 50; n++ {
+		d.Message(irc.ParseMessage("PRIVMSG #chan :" + strconv.Itoa(n)))
+	}
+	d.Close()
+
+	for n := 0; n < 50; n++ {
+		if msg := <-r.messages; msg.Trailer(1) != strconv.Itoa(n) {
+			t.Fatalf("Wrong order: %s at %d", msg.Trailer(1), n)
+		}
+	}
+}
+
+type blockingHandler struct {
+	recorder
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) Message(msg *irc.Message) {
+	h.started <- struct{}{}
+	<-h.release
+	h.recorder.Message(msg)
+}
+
+func TestDispatcherOverflow(t *testing.T) {
+	for _, overflow := range []Overflow{DropNewest, DropOldest} {
+		h := &blockingHandler{
+			recorder: *newRecorder(),
+			started:  make(chan struct{}, 8),
+			release:  make(chan struct{}),
+		}
+		d := NewDispatcher(h, 1, 2, overflow, nil)
+
+		// The first message is taken by the worker, two are queued
+		d.Message(irc.ParseMessage("PRIVMSG #chan :0"))
+		<-h.started
+		for n := 1; n < 5; n++ {
+			d.Message(irc.ParseMessage("PRIVMSG #chan :" + strconv.Itoa(n)))
+		}
+		close(h.release)
+		d.Close()
+
+		if s := d.Stats(); s.Dispatched != 5 || s.Dropped != 2 {
+			t.Errorf("Wrong stats: %+v", s)
+		}
+		want := map[Overflow][]string{DropNewest: {"0", "1", "2"}, DropOldest: {"0", "3", "4"}}[overflow]
+		for _, w := range want {
+			if msg := <-h.messages; msg.Trailer(1) != w {
+				t.Errorf("Wrong message for %v: %s != %s", overflow, msg.Trailer(1), w)
+			}
+		}
+	}
+}
+
+type panicHandler struct {
+	Mux
+}
+
+func (*panicHandler) Message(msg *irc.Message) {
+	if msg.Trailer(1) == "panic" {
+		panic("boom")
+	}
+}
+
+func TestDispatcherPanic(t *testing.T) {
+	panics := make(chan *Panic, 1)
+	d := NewDispatcher(&panicHandler{}, 1, 2, Block, nil)
+	d.PanicHandler = func(p *Panic) { panics <- p }
+	d.Message(irc.ParseMessage("PRIVMSG #chan :panic"))
+	d.Message(irc.ParseMessage("PRIVMSG #chan :fine"))
+	d.Close()
+
+	p := <-panics
+	if p.Value != "boom" || p.Callback != "Message" || p.Message.Trailer(1) != "panic" || len(p.Stack) == 0 {
+		t.Errorf("Wrong panic: %v", p)
+	}
+}
@@ -4,42 +4,35 @@ import (
 	"time"
 )
 
-type backoff struct {
-	mindur, maxdur time.Duration
-	last           time.Time
-	delay          time.Duration
-}
+// Adaptive is a ReconnectPolicy that grows the delay by Min for every session
+// that ended within the current delay, and halves it for longer sessions. The
+// time already spent in the session counts towards the delay.
+type Adaptive struct {
+	Min, Max time.Duration
 
-func newBackoff(minsec, maxsec int) *backoff {
-	return &backoff{
-		last:   time.Now(),
-		delay:  time.Second * time.Duration(minsec),
-		mindur: time.Second * time.Duration(minsec),
-		maxdur: time.Second * time.Duration(maxsec),
-	}
+	delay time.Duration
 }
 
-func (cd *backoff) Now() {
-	cd.last = time.Now()
-}
-
-func (cd *backoff) Delay() time.Duration {
-	delta := time.Since(cd.last)
+func (cd *Adaptive) Next(end SessionEnd) (time.Duration, bool) {
+	if cd.delay == 0 {
+		cd.delay = cd.Min
+	}
+	delta := end.Duration
 	if delta >= cd.delay {
 		// Time between last try as longer than the delay, so reduce
 		cd.delay = cd.delay / 2
-		if cd.delay < cd.mindur {
-			cd.delay = cd.mindur
+		if cd.delay < cd.Min {
+			cd.delay = cd.Min
 		}
 	} else {
-		cd.delay += cd.mindur
-		if cd.delay > cd.maxdur {
-			cd.delay = cd.maxdur
+		cd.delay += cd.Min
+		if cd.delay > cd.Max {
+			cd.delay = cd.Max
 		}
 	}
 	interval := cd.delay - delta
 	if interval < 0 {
 		interval = 0
 	}
-	return interval
+	return interval, true
 }
@@ -1,5 +1,14 @@
 package ircon
 
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"strconv"
+
+	"raccatta.cc/tmi/irc"
+)
+
 // IRCHandshake implements a standard pre-registered-state handshake for the
 // IRC protocol.
 type IRCHandshake struct {
@@ -10,40 +19,107 @@ type IRCHandshake struct {
 	Nick   string
 	Ident  string
 	GECOS  string
-	Passwd string
+	Passwd irc.Secret
+
+	// SASL authenticates during CAP negotiation, for IRC networks other than
+	// Twitch. It requires the handshake to be used through Converse.
+	SASL SASL
 }
 
 // TwitchHandshaker creates an IRCHandshake with some Twitch-specific details.
 func TwitchHandshaker(nick, passwd string) IRCHandshake {
 	if nick == "" {
 		// Default anonymous login; cannot send messages(!)
-		nick = "justinfan12345"
+		nick = anonymousNick()
 		passwd = "blah"
 	}
 	return IRCHandshake{
 		Caps:   DefaultCaps,
 		Nick:   nick,
 		Ident:  nick,
-		Passwd: passwd,
+		Passwd: irc.Secret(passwd),
 		GECOS:  nick,
 	}
 }
 
-func (irc IRCHandshake) Handshake(con Sender) error {
-	if irc.Caps != "" {
-		if err := con.Send("CAP REQ :" + irc.Caps); err != nil {
+// AnonymousHandshake is a Handshaker for read-only connections. Every
+// handshake uses a new random justinfan nick, so connections sharing it stay
+// distinct.
+type AnonymousHandshake struct {
+	// Caps overrides DefaultCaps if set.
+	Caps string
+}
+
+func (a AnonymousHandshake) Handshake(con Sender) error {
+	h := TwitchHandshaker("", "")
+	if a.Caps != "" {
+		h.Caps = a.Caps
+	}
+	return h.Handshake(con)
+}
+
+func anonymousNick() string {
+	var b [4]byte
+	rand.Read(b[:])
+	return "justinfan" + strconv.Itoa(int(binary.BigEndian.Uint32(b[:])%100000))
+}
+
+func (h IRCHandshake) Handshake(con Sender) error {
+	if h.SASL != nil {
+		return ErrSASLConversation
+	}
+	return h.redact(h.handshake(con))
+}
+
+// Converses reports whether the handshake needs the server's responses, which
+// is the case with SASL.
+func (h IRCHandshake) Converses() bool {
+	return h.SASL != nil
+}
+
+// Converse performs the handshake, and SASL authentication if set.
+func (h IRCHandshake) Converse(con Sender, msgs <-chan *irc.Message) error {
+	if h.SASL == nil {
+		return h.Handshake(con)
+	}
+	return h.redact(h.sasl(con, msgs))
+}
+
+// redact keeps credentials out of errors of transports quoting the failed
+// line.
+func (h IRCHandshake) redact(err error) error {
+	secrets := []string{string(h.Passwd)}
+	if h.SASL != nil {
+		enc := base64.StdEncoding.EncodeToString(h.SASL.Payload())
+		for len(enc) > saslChunk {
+			secrets = append(secrets, enc[:saslChunk])
+			enc = enc[saslChunk:]
+		}
+		secrets = append(secrets, enc)
+	}
+	return irc.RedactError(err, secrets...)
+}
+
+func (h IRCHandshake) handshake(con Sender) error {
+	if h.Caps != "" {
+		if err := con.Send("CAP REQ :" + h.Caps); err != nil {
 			return err
 		}
 	}
-	if irc.Passwd != "" {
-		if err := con.Send("PASS " + irc.Passwd); err != nil {
+	return h.register(con)
+}
+
+// register sends the registration commands.
+func (h IRCHandshake) register(con Sender) error {
+	if h.Passwd != "" {
+		if err := con.Send("PASS " + string(h.Passwd)); err != nil {
 			return err
 		}
 	}
-	if err := con.Send("NICK " + irc.Nick); err != nil {
+	if err := con.Send("NICK " + h.Nick); err != nil {
 		return err
 	}
-	if err := con.Send("USER " + irc.Ident + " 8 * :" + irc.GECOS); err != nil {
+	if err := con.Send("USER " + h.Ident + " 8 * :" + h.GECOS); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,54 @@
+package ircon
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// quotingSender fails like a transport quoting the failed line.
+type quotingSender struct{}
+
+func (quotingSender) Send(s string) error {
+	return fmt.Errorf("Cannot send %q", s)
+}
+
+func TestHandshakeRedacts(t *testing.T) {
+	h := TwitchHandshaker("someone", "oauth:s3cr3t")
+	h.Caps = ""
+	b, _ := json.Marshal(h)
+	for _, s := range []string{
+		fmt.Sprintf("%v %+v %#v", h, h, h),
+		string(b),
+		h.Handshake(quotingSender{}).Error(),
+	} {
+		if strings.Contains(s, "s3cr3t") {
+			t.Errorf("Token not redacted: %s", s)
+		}
+	}
+}
+
+func TestAnonymousHandshake(t *testing.T) {
+	nicks := make(map[string]bool)
+	for n := 0; n < 3; n++ {
+		var l lines
+		if err := (AnonymousHandshake{}).Handshake(&l); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range l {
+			if strings.HasPrefix(line, "NICK ") {
+				nicks[line] = true
+			}
+		}
+	}
+	// A repeated nick among three handshakes is possible, but unlikely
+	if len(nicks) < 2 {
+		t.Errorf("Nick not renewed: %v", nicks)
+	}
+	for nick := range nicks {
+		if !strings.HasPrefix(nick, "NICK justinfan") {
+			t.Errorf("Wrong nick: %s", nick)
+		}
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
+	"raccatta.cc/tmi/clock"
 	"raccatta.cc/tmi/irc"
 )
 
@@ -30,6 +34,15 @@ type (
 	Handshaker interface {
 		Handshake(s Sender) error
 	}
+
+	// A Conversation is a Handshaker that reads the server's responses, e.g.
+	// for SASL. IRCon calls Converse instead of Handshake if Converses returns
+	// true. msgs is closed when the connection ends.
+	Conversation interface {
+		Handshaker
+		Converses() bool
+		Converse(s Sender, msgs <-chan *irc.Message) error
+	}
 )
 
 // DefaultCaps is the default set of capabilities. The twitch.tv/membership
@@ -61,65 +74,209 @@ type IRCon struct {
 	// Server allows connecting to a different TMI server.
 	Server string
 
+	// Rooms tracks the state of joined channels.
+	Rooms RoomTracker
+
+	// Self tracks the state of the connected user, e.g. to make permission
+	// decisions.
+	Self SelfTracker
+
+	// EnforceRoomState makes Send wait out slow mode, and refuse messages that
+	// subscribers-only or followers-only mode would reject.
+	EnforceRoomState bool
+
+	// AckTimeout is the time SendAck waits for an acknowledgement. If zero,
+	// DefaultAckTimeout is used.
+	AckTimeout time.Duration
+
+	// JoinTimeout is the time a JOIN keeps the state in Joining without an
+	// answer. If zero, DefaultJoinTimeout is used.
+	JoinTimeout time.Duration
+
+	// Reconnect decides when to reconnect. If nil, DefaultReconnectPolicy is
+	// used.
+	Reconnect ReconnectPolicy
+
+	// Clock is used for all timing. If nil, clock.System is used.
+	Clock clock.Clock
+
+	// PingInterval enables sending a PING with this interval, to detect dead
+	// connections early and measure latency. If the PONG takes longer than
+	// PingTimeout, the connection is restarted.
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+
+	// PanicHandler is called when a Handler callback panics. If nil, the
+	// panic is logged. The session continues, unless RestartOnPanic is set.
+	PanicHandler   func(*Panic)
+	RestartOnPanic bool
+
+	// PartOnShutdown and QuitOnShutdown make Shutdown PART the joined
+	// channels and send QUIT before closing the connection.
+	PartOnShutdown bool
+	QuitOnShutdown bool
+
+	// Logger receives events such as state transitions, reconnect delays and
+	// negotiated capabilities. Pass irc.WithLogger to the Dialer to log dial
+	// attempts and raw lines.
+	Logger *slog.Logger
+
+	// Metrics receives metrics events, e.g. from Metrics.For.
+	Metrics Collector
+
 	dialer     irc.Dialer
 	handshaker Handshaker
 	con        *conn
 	mu         sync.Mutex
+	acks       acks
+	sm         stateMachine
+
+	// closing, sends, cancel and stopped implement Shutdown
+	closing bool
+	sends   sync.WaitGroup
+	cancel  context.CancelFunc
+	stopped chan struct{}
+
+	middlewares []Middleware
+
+	statsMu sync.Mutex
+	stats   Stats
 }
 
 // New creates a new IRCon with the given credentials.
 func New(d irc.Dialer, h Handshaker) *IRCon {
-	return &IRCon{
+	i := &IRCon{
 		dialer:     d,
 		handshaker: h,
 	}
+	i.acks.joining = i.joining
+	return i
 }
 
-// Run maintains a connection to the IRC server until the context is done.
+// Run maintains a connection to the IRC server until the context is done,
+// Shutdown is called, or the ReconnectPolicy gives up.
 func (i *IRCon) Run(ctx context.Context, h Handler) {
-	i.loop(ctx, h)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopped := make(chan struct{})
+	defer close(stopped)
+	i.mu.Lock()
+	i.closing = false
+	i.cancel = cancel
+	i.stopped = stopped
+	i.mu.Unlock()
+
+	i.loop(ctx, guard{i: i, h: Chain(h, i.middlewares...)})
+}
+
+func (i *IRCon) clock() clock.Clock {
+	if i.Clock == nil {
+		return clock.System
+	}
+	return i.Clock
 }
 
 func (i *IRCon) loop(ctx context.Context, h Handler) {
-	cd := newBackoff(15, 300)
-	delay := time.After(0)
+	policy := i.Reconnect
+	if policy == nil {
+		policy = DefaultReconnectPolicy()
+	}
+	clk := i.clock()
+	attempt := 0
+	delay := clk.After(0)
 	for {
 		i.mu.Lock()
 		i.con = nil
 		i.mu.Unlock()
 		select {
 		case <-ctx.Done():
+			i.setState(Stopped, ctx.Err())
 			return
 		case <-delay:
 		}
-		cd.Now()
-		i.session(ctx, h)
-		delay = time.After(cd.Delay())
+		i.setState(Dialing, nil)
+		start := clk.Now()
+		end := i.session(ctx, h)
+		end.Start = start
+		end.Duration = clk.Now().Sub(start)
+		if i.Metrics != nil {
+			i.Metrics.SessionEnded(end)
+		}
+		if ctx.Err() != nil {
+			i.setState(Stopped, end.Err)
+			return
+		}
+		if end.Connected && end.Duration >= StableSession {
+			attempt = 0
+		}
+		attempt++
+		end.Attempt = attempt
+		d, ok := policy.Next(end)
+		if !ok {
+			i.setState(Stopped, end.Err)
+			return
+		}
+		i.setState(BackingOff, end.Err)
+		if i.Metrics != nil {
+			i.Metrics.Reconnecting(end, d)
+		}
+		i.log().Info("Reconnecting", "delay", d, "attempt", attempt, "err", end.Err)
+		delay = clk.After(d)
 	}
 }
 
-func (i *IRCon) session(ctx context.Context, h Handler) {
+var errReconnect = errors.New("Server requested reconnect")
+
+func (i *IRCon) session(ctx context.Context, h Handler) SessionEnd {
 	server := i.Server
 	if server == "" {
 		server = DefaultServer
 	}
 
+	start := i.clock().Now()
 	con, err := i.dialer.Dial(ctx)
 	if err != nil {
 		h.Disconnected(err)
-		return
+		return SessionEnd{Err: err}
 	}
+	if i.Metrics != nil {
+		con = meteredConn{Conn: con, c: i.Metrics}
+	}
+	i.setState(Handshaking, nil)
 	c := &conn{
-		Conn: con,
-		h:    h,
+		Conn:  con,
+		h:     h,
+		id:    newNonce()[:8],
+		pongs: make(chan string, 1),
 	}
 	i.mu.Lock()
 	i.con = c
 	i.mu.Unlock()
 	wait := make(chan struct{})
+	// welcomed is set by the reader on 001, and read after wait is closed
+	welcomed := false
+	conversation, _ := i.handshaker.(Conversation)
+	if conversation != nil && !conversation.Converses() {
+		conversation = nil
+	}
+	var replies chan *irc.Message
+	handshaked := make(chan struct{})
+	if conversation != nil {
+		replies = make(chan *irc.Message)
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.closeWithErr(ctx.Err())
+		case <-wait:
+		}
+	}()
 	go func() {
 		defer close(wait)
 		defer con.Close()
+		if replies != nil {
+			defer close(replies)
+		}
 		for {
 			msg, err := con.Read()
 			if err != nil {
@@ -127,8 +284,33 @@ func (i *IRCon) session(ctx context.Context, h Handler) {
 				return
 			}
 
-			if msg.Command == "PING" {
-				con.Send("PONG :" + msg.Trailer(0))
+			switch msg.Command {
+			case "PING":
+				c.Send("PONG :" + msg.Trailer(0))
+			case "PONG":
+				select {
+				case c.pongs <- msg.Trailer(1):
+				default:
+				}
+			case "RECONNECT":
+				c.closeWithErr(errReconnect)
+			case "CAP":
+				i.logCap(msg)
+			case "001":
+				welcomed = true
+				if i.Metrics != nil {
+					i.Metrics.Handshaked(i.clock().Now().Sub(start))
+				}
+			}
+			i.Rooms.Message(msg)
+			i.Self.Message(msg)
+			i.acks.Message(msg)
+			i.track(msg)
+			if replies != nil {
+				select {
+				case replies <- msg:
+				case <-handshaked:
+				}
 			}
 
 			// Call should not block
@@ -136,10 +318,33 @@ func (i *IRCon) session(ctx context.Context, h Handler) {
 			h.Message(msg)
 		}
 	}()
-	if err := i.handshaker.Handshake(con); err == nil {
+	if conversation != nil {
+		err = conversation.Converse(c, replies)
+	} else {
+		err = i.handshaker.Handshake(c)
+	}
+	close(handshaked)
+	if err == nil {
 		h.Connected()
+		if i.PingInterval > 0 {
+			go i.keepalive(c, wait)
+		}
+	} else {
+		i.log().Warn("Handshake failed", "err", err)
+		c.closeWithErr(fmt.Errorf("Handshake failed: %w", err))
 	}
 	<-wait
+	i.Rooms.Reset()
+	i.Self.Reset()
+	i.acks.reset(ErrNotConnected)
+
+	// The reader closed the connection before returning, so err is set
+	err = c.err
+	return SessionEnd{
+		Err:       err,
+		Connected: welcomed,
+		Clean:     errors.Is(err, errReconnect) || errors.Is(err, io.EOF),
+	}
 }
 
 // Send sends a message to the currently active IRC connection. If there is no
@@ -149,11 +354,57 @@ func (i *IRCon) session(ctx context.Context, h Handler) {
 // connection instance, and not confuse it during reconnects.
 // TODO: make a variant that can wait for a succesful connection.
 func (i *IRCon) Send(s string) error {
+	return i.SendContext(context.Background(), s)
+}
+
+// SendContext is like Send, but the context governs waiting for slow mode
+// when EnforceRoomState is set.
+func (i *IRCon) SendContext(ctx context.Context, s string) error {
+	return i.sendContext(ctx, s, nil)
+}
+
+// sendContext implements SendContext. If set, sending is called just before
+// the line is written.
+func (i *IRCon) sendContext(ctx context.Context, s string, sending func()) (err error) {
+	if i.Metrics != nil {
+		defer func() {
+			if err != nil {
+				i.Metrics.SendFailed(err)
+			}
+		}()
+	}
+	if err := i.beginSend(); err != nil {
+		return err
+	}
+	defer i.sends.Done()
+	if i.EnforceRoomState {
+		undo, err := i.Rooms.admit(ctx, s, &i.Self, i.clock())
+		if err != nil {
+			return err
+		}
+		if err := i.send(s, sending); err != nil {
+			// A failed send does not use up the slow mode slot
+			undo()
+			return err
+		}
+	} else if err := i.send(s, sending); err != nil {
+		return err
+	}
+	if strings.HasPrefix(s, "JOIN ") {
+		i.sentJoin(s)
+	}
+	return nil
+}
+
+func (i *IRCon) send(s string, sending func()) error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 	if i.con == nil {
 		return ErrNotConnected
 	}
+	if sending != nil {
+		sending()
+	}
 	err := i.con.Send(s)
 	if err != nil {
 		i.con.closeWithErr(fmt.Errorf("Send failed: %w", err))
@@ -161,16 +412,62 @@ func (i *IRCon) Send(s string) error {
 	return err
 }
 
-var ErrNotConnected = errors.New("Not connected")
+// SendMessage encodes and sends a message, e.g. one carrying client-only tags.
+func (i *IRCon) SendMessage(ctx context.Context, msg *irc.Message) error {
+	return i.SendContext(ctx, msg.Encode())
+}
+
+// Say sends a message to a channel.
+func (i *IRCon) Say(ctx context.Context, channel, text string) error {
+	return i.SendMessage(ctx, &irc.Message{
+		Command:    "PRIVMSG",
+		Args:       []string{"#" + normalizeChannel(channel), text},
+		HasTrailer: true,
+	})
+}
+
+// Reply sends a threaded reply to a PRIVMSG, to the channel it was sent in.
+func (i *IRCon) Reply(ctx context.Context, parent *irc.Message, text string) error {
+	id := parent.Tags["id"]
+	if id == "" {
+		return ErrNoMessageID
+	}
+	return i.SendMessage(ctx, &irc.Message{
+		Tags:       map[string]string{"reply-parent-msg-id": id},
+		Command:    "PRIVMSG",
+		Args:       []string{parent.Arg(0), text},
+		HasTrailer: true,
+	})
+}
+
+var (
+	ErrNotConnected = errors.New("Not connected")
+	ErrNoMessageID  = errors.New("Message has no id")
+)
 
 type conn struct {
 	irc.Conn
 	disconnected sync.Once
 	h            Handler
+	err          error
+
+	// wmu serializes writes from the reader, keepalive, Shutdown and Send
+	wmu sync.Mutex
+
+	// id distinguishes the PING tokens of connections
+	id    string
+	pongs chan string
+}
+
+func (c *conn) Send(s string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.Conn.Send(s)
 }
 
 func (c *conn) closeWithErr(err error) {
 	c.disconnected.Do(func() {
+		c.err = err
 		c.h.Disconnected(err)
 	})
 	c.Close()
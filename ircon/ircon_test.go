@@ -0,0 +1,37 @@
+package ircon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestReply(t *testing.T) {
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	i := New(d, TwitchHandshaker("bot", "oauth:x"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go i.Run(ctx, newRecorder())
+	c.in <- ":tmi.twitch.tv 001 bot :Welcome, GLHF!"
+	if err := i.WaitFor(ctx, Registered); err != nil {
+		t.Fatal(err)
+	}
+
+	parent := irc.ParseMessage("@id=b34ccfc7-4977-403a-8a94-33c6bac34fb8 :someone!someone@someone.tmi.twitch.tv PRIVMSG #chan :hello")
+	if err := i.Reply(ctx, parent, "hi there"); err != nil {
+		t.Fatal(err)
+	}
+	want := "@reply-parent-msg-id=b34ccfc7-4977-403a-8a94-33c6bac34fb8 PRIVMSG #chan :hi there"
+	if line := c.expect("@"); line != want {
+		t.Errorf("Wrong reply: %q", line)
+	}
+
+	if err := i.Reply(ctx, irc.ParseMessage(":someone!someone@someone.tmi.twitch.tv PRIVMSG #chan :hello"), "hi"); err != ErrNoMessageID {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
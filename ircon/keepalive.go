@@ -0,0 +1,61 @@
+package ircon
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// DefaultPingTimeout is the time to wait for a PONG if PingTimeout is not set.
+const DefaultPingTimeout = 10 * time.Second
+
+var ErrPingTimeout = errors.New("PING timeout")
+
+// keepalive sends a PING every PingInterval until stop is closed, and closes
+// the connection if the matching PONG is not received within PingTimeout.
+func (i *IRCon) keepalive(c *conn, stop <-chan struct{}) {
+	clk := i.clock()
+	timeout := i.PingTimeout
+	if timeout <= 0 {
+		timeout = DefaultPingTimeout
+	}
+	for n := 1; ; n++ {
+		select {
+		case <-stop:
+			return
+		case <-clk.After(i.PingInterval):
+		}
+
+		token := "tmi-" + c.id + "-" + strconv.Itoa(n)
+		sent := clk.Now()
+		if err := c.Send("PING :" + token); err != nil {
+			return
+		}
+		i.statsMu.Lock()
+		i.stats.Pings++
+		i.statsMu.Unlock()
+
+		expired := clk.After(timeout)
+	wait:
+		for {
+			select {
+			case <-stop:
+				return
+			case <-expired:
+				c.closeWithErr(ErrPingTimeout)
+				return
+			case pong := <-c.pongs:
+				if pong != token {
+					continue
+				}
+				rtt := clk.Now().Sub(sent)
+				i.statsMu.Lock()
+				i.stats.Pongs++
+				i.stats.LastLatency = rtt
+				i.stats.Latency.observe(rtt)
+				i.statsMu.Unlock()
+				break wait
+			}
+		}
+	}
+}
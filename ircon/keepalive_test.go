@@ -0,0 +1,56 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+)
+
+func TestKeepalive(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	i := New(d, TwitchHandshaker("", ""))
+	i.Clock = clk
+	i.PingInterval = time.Minute
+	i.PingTimeout = 5 * time.Second
+	r := newRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go i.Run(ctx, r)
+	<-r.connected
+
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(time.Minute)
+	token := strings.TrimPrefix(c.expect("PING :"), "PING :")
+	clk.Advance(100 * time.Millisecond)
+	c.in <- ":tmi.twitch.tv PONG tmi.twitch.tv :" + token
+	<-r.messages
+
+	// Wait for the next interval; the expired PONG timeout is still pending
+	for i.Stats().Pongs == 0 || clk.Waiters() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if s := i.Stats(); s.Pongs != 1 || s.LastLatency != 100*time.Millisecond {
+		t.Errorf("Wrong stats: %+v", s)
+	}
+
+	// No PONG restarts the session
+	clk.Advance(time.Minute)
+	c.expect("PING :")
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clk.Advance(5 * time.Second)
+	if err := <-r.disconnected; !errors.Is(err, ErrPingTimeout) {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package ircon
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// discard is a Handler dropping all records, for when no Logger is set.
+type discard struct{}
+
+func (discard) Enabled(context.Context, slog.Level) bool  { return false }
+func (discard) Handle(context.Context, slog.Record) error { return nil }
+func (d discard) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discard) WithGroup(string) slog.Handler           { return d }
+
+var nopLogger = slog.New(discard{})
+
+func (i *IRCon) log() *slog.Logger {
+	if i.Logger == nil {
+		return nopLogger
+	}
+	return i.Logger
+}
+
+// logCap logs the server's answer to a CAP REQ.
+func (i *IRCon) logCap(msg *irc.Message) {
+	caps := strings.Fields(msg.Trailer(2))
+	switch msg.Arg(1) {
+	case "ACK":
+		i.log().Info("Capabilities acknowledged", "caps", caps)
+	case "NAK":
+		i.log().Warn("Capabilities rejected", "caps", caps)
+	}
+}
@@ -0,0 +1,300 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A Collector receives metrics events from an IRCon. Calls are made
+// synchronously from the connection's goroutines and should not block.
+type Collector interface {
+	// Received and Sent are called for every line, with its size including
+	// the line ending.
+	Received(command string, bytes int)
+	Sent(command string, bytes int)
+
+	// SendFailed is called when Send returns an error.
+	SendFailed(err error)
+
+	// Handshaked is called when the server welcomed the connection, with the
+	// time since dialing started.
+	Handshaked(d time.Duration)
+
+	// SessionEnded is called after every session, and Reconnecting before
+	// waiting to start the next.
+	SessionEnded(end SessionEnd)
+	Reconnecting(end SessionEnd, delay time.Duration)
+}
+
+// Cause classifies an error ending a session or failing a send, e.g. as a
+// metrics label.
+func Cause(err error) string {
+	var p *Panic
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, ErrShutdown):
+		return "shutdown"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "context"
+	case errors.Is(err, errReconnect):
+		return "reconnect"
+	case errors.Is(err, ErrPingTimeout):
+		return "ping_timeout"
+	case errors.Is(err, ErrNotConnected):
+		return "not_connected"
+	case errors.Is(err, ErrSubsOnly), errors.Is(err, ErrFollowersOnly):
+		return "room_state"
+	case errors.As(err, &p):
+		return "panic"
+	case errors.Is(err, io.EOF):
+		return "eof"
+	}
+	return "error"
+}
+
+// meteredConn reports the lines of a connection to a Collector.
+type meteredConn struct {
+	irc.Conn
+	c Collector
+}
+
+func (m meteredConn) Read() (*irc.Message, error) {
+	msg, err := m.Conn.Read()
+	if msg != nil {
+		m.c.Received(msg.Command, len(msg.Raw())+2)
+	}
+	return msg, err
+}
+
+func (m meteredConn) Send(s string) error {
+	err := m.Conn.Send(s)
+	if err == nil {
+		m.c.Sent(irc.ParseMessage(s).Command, len(s)+2)
+	}
+	return err
+}
+
+// Buckets of the duration histograms of Metrics, in seconds.
+var (
+	HandshakeBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+	SessionBuckets   = []float64{60, 300, 900, 3600, 6 * 3600, 24 * 3600}
+)
+
+// Metrics is a Collector aggregating the metrics of any number of
+// connections, and an http.Handler serving them in the Prometheus text
+// exposition format.
+type Metrics struct {
+	// Namespace prefixes the metric names. If empty, "tmi" is used.
+	Namespace string
+
+	mu         sync.Mutex
+	counters   map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+type metric struct {
+	name, help, typ string
+	buckets         []float64
+}
+
+var metrics = []metric{
+	{name: "messages_received_total", help: "Lines received by command.", typ: "counter"},
+	{name: "messages_sent_total", help: "Lines sent by command.", typ: "counter"},
+	{name: "received_bytes_total", help: "Bytes received.", typ: "counter"},
+	{name: "sent_bytes_total", help: "Bytes sent.", typ: "counter"},
+	{name: "send_errors_total", help: "Failed sends by cause.", typ: "counter"},
+	{name: "reconnects_total", help: "Reconnects by the cause of the ended session.", typ: "counter"},
+	{name: "handshake_duration_seconds", help: "Time from dialing until the server welcomed the connection.", typ: "histogram", buckets: HandshakeBuckets},
+	{name: "session_duration_seconds", help: "Lifetime of connected sessions.", typ: "histogram", buckets: SessionBuckets},
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *histogram) observe(v float64) {
+	for n, b := range h.buckets {
+		if v <= b {
+			h.counts[n]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// For returns a Collector for a connection, labeling its metrics with
+// conn="name" unless name is empty. Metrics itself collects without labels.
+func (m *Metrics) For(name string) Collector {
+	return labeledCollector{m: m, conn: name}
+}
+
+func (m *Metrics) Received(command string, bytes int) { m.For("").Received(command, bytes) }
+func (m *Metrics) Sent(command string, bytes int)     { m.For("").Sent(command, bytes) }
+func (m *Metrics) SendFailed(err error)               { m.For("").SendFailed(err) }
+func (m *Metrics) Handshaked(d time.Duration)         { m.For("").Handshaked(d) }
+func (m *Metrics) SessionEnded(end SessionEnd)        { m.For("").SessionEnded(end) }
+
+func (m *Metrics) Reconnecting(end SessionEnd, delay time.Duration) {
+	m.For("").Reconnecting(end, delay)
+}
+
+func (m *Metrics) add(name, labels string, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counters == nil {
+		m.counters = make(map[string]map[string]float64)
+	}
+	if m.counters[name] == nil {
+		m.counters[name] = make(map[string]float64)
+	}
+	m.counters[name][labels] += v
+}
+
+func (m *Metrics) observe(name, labels string, buckets []float64, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.histograms == nil {
+		m.histograms = make(map[string]map[string]*histogram)
+	}
+	if m.histograms[name] == nil {
+		m.histograms[name] = make(map[string]*histogram)
+	}
+	h := m.histograms[name][labels]
+	if h == nil {
+		h = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		m.histograms[name][labels] = h
+	}
+	h.observe(v)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	ns := m.Namespace
+	if ns == "" {
+		ns = "tmi"
+	}
+	var b strings.Builder
+	m.mu.Lock()
+	for _, def := range metrics {
+		name := ns + "_" + def.name
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, def.help, name, def.typ)
+		if def.typ == "counter" {
+			series := m.counters[def.name]
+			for _, labels := range sortedKeys(series) {
+				fmt.Fprintf(&b, "%s%s %s\n", name, braces(labels), formatFloat(series[labels]))
+			}
+			continue
+		}
+		series := m.histograms[def.name]
+		for _, labels := range sortedKeys(series) {
+			h := series[labels]
+			for n, bound := range h.buckets {
+				le := `le="` + formatFloat(bound) + `"`
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(join(labels, le)), h.counts[n])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(join(labels, `le="+Inf"`)), h.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", name, braces(labels), formatFloat(h.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, braces(labels), h.count)
+		}
+	}
+	m.mu.Unlock()
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics, e.g. on /metrics.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// labeledCollector is a Collector adding a conn label to Metrics.
+type labeledCollector struct {
+	m    *Metrics
+	conn string
+}
+
+func (c labeledCollector) labels(name, value string) string {
+	var l string
+	if c.conn != "" {
+		l = label("conn", c.conn)
+	}
+	if name != "" {
+		l = join(l, label(name, value))
+	}
+	return l
+}
+
+func (c labeledCollector) Received(command string, bytes int) {
+	c.m.add("messages_received_total", c.labels("command", command), 1)
+	c.m.add("received_bytes_total", c.labels("", ""), float64(bytes))
+}
+
+func (c labeledCollector) Sent(command string, bytes int) {
+	c.m.add("messages_sent_total", c.labels("command", command), 1)
+	c.m.add("sent_bytes_total", c.labels("", ""), float64(bytes))
+}
+
+func (c labeledCollector) SendFailed(err error) {
+	c.m.add("send_errors_total", c.labels("cause", Cause(err)), 1)
+}
+
+func (c labeledCollector) Handshaked(d time.Duration) {
+	c.m.observe("handshake_duration_seconds", c.labels("", ""), HandshakeBuckets, d.Seconds())
+}
+
+func (c labeledCollector) SessionEnded(end SessionEnd) {
+	if end.Connected {
+		c.m.observe("session_duration_seconds", c.labels("", ""), SessionBuckets, end.Duration.Seconds())
+	}
+}
+
+func (c labeledCollector) Reconnecting(end SessionEnd, delay time.Duration) {
+	c.m.add("reconnects_total", c.labels("cause", Cause(end.Err)), 1)
+}
+
+func label(name, value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return name + `="` + r.Replace(value) + `"`
+}
+
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+package ircon
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+)
+
+func TestMetrics(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	m := &Metrics{}
+	i := New(d, TwitchHandshaker("", ""))
+	i.Clock = clk
+	i.Metrics = m.For("bot")
+	i.Reconnect = Fixed{Delay: time.Hour}
+	r := newRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go i.Run(ctx, r)
+	<-r.connected
+
+	c.expect("NICK ")
+	clk.Advance(300 * time.Millisecond)
+	c.in <- ":tmi.twitch.tv 001 justinfan1 :Welcome, GLHF!"
+	<-r.messages
+	if err := i.Send("JOIN #foo"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	<-r.disconnected
+	i.WaitFor(ctx, BackingOff)
+	if err := i.Send("PRIVMSG #foo :hi"); err != ErrNotConnected {
+		t.Fatalf("Wrong error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, nil)
+	out := w.Body.String()
+	for _, want := range []string{
+		"# TYPE tmi_messages_received_total counter\n",
+		`tmi_messages_received_total{conn="bot",command="001"} 1` + "\n",
+		`tmi_messages_sent_total{conn="bot",command="JOIN"} 1` + "\n",
+		`tmi_received_bytes_total{conn="bot"} 47` + "\n",
+		`tmi_send_errors_total{conn="bot",cause="not_connected"} 1` + "\n",
+		`tmi_reconnects_total{conn="bot",cause="eof"} 1` + "\n",
+		`tmi_handshake_duration_seconds_bucket{conn="bot",le="0.25"} 0` + "\n",
+		`tmi_handshake_duration_seconds_bucket{conn="bot",le="0.5"} 1` + "\n",
+		`tmi_handshake_duration_seconds_bucket{conn="bot",le="+Inf"} 1` + "\n",
+		`tmi_handshake_duration_seconds_sum{conn="bot"} 0.3` + "\n",
+		`tmi_session_duration_seconds_count{conn="bot"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Missing %q in:\n%s", want, out)
+		}
+	}
+}
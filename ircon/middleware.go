@@ -0,0 +1,143 @@
+package ircon
+
+import (
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A Middleware wraps a Handler, typically to process or filter messages before
+// passing them on.
+type Middleware func(next Handler) Handler
+
+// Chain wraps h with middlewares. The first middleware sees messages first.
+func Chain(h Handler, middlewares ...Middleware) Handler {
+	for n := len(middlewares) - 1; n >= 0; n-- {
+		h = middlewares[n](h)
+	}
+	return h
+}
+
+// Use adds middlewares around the Handler passed to Run. It must be called
+// before Run.
+func (i *IRCon) Use(middlewares ...Middleware) {
+	i.middlewares = append(i.middlewares, middlewares...)
+}
+
+// MessageMiddleware creates a Middleware from a function handling messages.
+// Connected and Disconnected are passed on unchanged.
+func MessageMiddleware(fn func(next Handler, msg *irc.Message)) Middleware {
+	return func(next Handler) Handler {
+		return messageHandler{Handler: next, fn: fn}
+	}
+}
+
+type messageHandler struct {
+	Handler
+	fn func(next Handler, msg *irc.Message)
+}
+
+func (h messageHandler) Message(msg *irc.Message) {
+	h.fn(h.Handler, msg)
+}
+
+// Filter passes on only the messages keep returns true for.
+func Filter(keep func(*irc.Message) bool) Middleware {
+	return MessageMiddleware(func(next Handler, msg *irc.Message) {
+		if keep(msg) {
+			next.Message(msg)
+		}
+	})
+}
+
+// Recover recovers from panics in Message, and reports them with the message
+// and stack trace.
+func Recover(report func(v interface{}, msg *irc.Message, stack []byte)) Middleware {
+	return MessageMiddleware(func(next Handler, msg *irc.Message) {
+		defer func() {
+			if v := recover(); v != nil {
+				report(v, msg, debug.Stack())
+			}
+		}()
+		next.Message(msg)
+	})
+}
+
+// Logging logs every message and connection event, with credentials redacted.
+func Logging(l *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return logHandler{Handler: next, l: l}
+	}
+}
+
+type logHandler struct {
+	Handler
+	l *slog.Logger
+}
+
+func (h logHandler) Connected() {
+	h.l.Info("Connected")
+	h.Handler.Connected()
+}
+
+func (h logHandler) Disconnected(err error) {
+	h.l.Info("Disconnected", "err", err)
+	h.Handler.Disconnected(err)
+}
+
+func (h logHandler) Message(msg *irc.Message) {
+	h.l.Info("Message", "line", irc.RedactLine(msg.Raw()))
+	h.Handler.Message(msg)
+}
+
+// Sample passes on every nth message of the given commands, or of all
+// messages if none are given. Other messages pass unchanged.
+func Sample(n int, commands ...string) Middleware {
+	var count uint64
+	return Filter(func(msg *irc.Message) bool {
+		if len(commands) > 0 && !contains(commands, msg.Command) {
+			return true
+		}
+		return n <= 1 || atomic.AddUint64(&count, 1)%uint64(n) == 1
+	})
+}
+
+// IgnoreUsers drops messages from the given users.
+func IgnoreUsers(logins ...string) Middleware {
+	return Filter(func(msg *irc.Message) bool {
+		return !contains(logins, ByUser(msg))
+	})
+}
+
+// IgnoreChannels drops messages in the given channels.
+func IgnoreChannels(channels ...string) Middleware {
+	ignored := make([]string, len(channels))
+	for n, ch := range channels {
+		ignored[n] = normalizeChannel(ch)
+	}
+	return Filter(func(msg *irc.Message) bool {
+		ch := ByChannel(msg)
+		return ch == "" || !contains(ignored, normalizeChannel(ch))
+	})
+}
+
+// IgnoreSelf drops messages sent by the user of a connection, e.g. the writer
+// of a Client as seen by its readers.
+func IgnoreSelf(self *SelfTracker) Middleware {
+	return Filter(func(msg *irc.Message) bool {
+		nick := self.Nick()
+		return nick == "" || !strings.EqualFold(ByUser(msg), nick)
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package ircon
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := newRecorder()
+	var self SelfTracker
+	self.Message(irc.ParseMessage(":tmi.twitch.tv 001 bot :Welcome, GLHF!"))
+
+	var panics int
+	h := Chain(&Mux{Default: func(msg *irc.Message) {
+		if msg.Trailer(1) == "boom" {
+			panic("boom")
+		}
+		r.Message(msg)
+	}},
+		Recover(func(v interface{}, msg *irc.Message, stack []byte) { panics++ }),
+		IgnoreSelf(&self),
+		IgnoreUsers("spammer"),
+		IgnoreChannels("#quiet"),
+	)
+
+	for _, line := range []string{
+		":bot!bot@bot.tmi.twitch.tv PRIVMSG #chan :mine",
+		":spammer!spammer@spammer.tmi.twitch.tv PRIVMSG #chan :spam",
+		":user!user@user.tmi.twitch.tv PRIVMSG #quiet :shh",
+		":user!user@user.tmi.twitch.tv PRIVMSG #chan :boom",
+		":user!user@user.tmi.twitch.tv PRIVMSG #chan :hi",
+	} {
+		h.Message(irc.ParseMessage(line))
+	}
+
+	if panics != 1 {
+		t.Errorf("Wrong panics: %d", panics)
+	}
+	if len(r.messages) != 1 {
+		t.Fatalf("Wrong messages: %d", len(r.messages))
+	}
+	if msg := <-r.messages; msg.Trailer(1) != "hi" {
+		t.Errorf("Wrong message: %v", msg)
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var b strings.Builder
+	h := Chain(newRecorder(), Logging(slog.New(slog.NewTextHandler(&b, nil))))
+	h.Connected()
+	h.Message(irc.ParseMessage("PASS oauth:s3cr3t"))
+	h.Message(irc.ParseMessage(":user!user@user.tmi.twitch.tv PRIVMSG #chan :hi"))
+	h.Disconnected(io.EOF)
+
+	s := b.String()
+	for _, want := range []string{"msg=Connected", `line="PASS [REDACTED]"`, `line=":user!user@user.tmi.twitch.tv PRIVMSG #chan :hi"`, "msg=Disconnected err=EOF"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Missing %s in log:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "s3cr3t") {
+		t.Errorf("Credential logged:\n%s", s)
+	}
+}
@@ -0,0 +1,108 @@
+package ircon
+
+import (
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+// A Mux is a Handler that decodes messages and dispatches them to typed
+// callbacks. Messages without a matching callback are passed to Default, if
+// it is set.
+type Mux struct {
+	OnConnected    func()
+	OnDisconnected func(err error)
+
+	OnPrivmsg         func(*twitch.Privmsg)
+	OnUserNotice      func(*twitch.UserNotice)
+	OnClearChat       func(*twitch.ClearChat)
+	OnClearMsg        func(*twitch.ClearMsg)
+	OnRoomState       func(*twitch.RoomState)
+	OnUserState       func(*twitch.UserState)
+	OnGlobalUserState func(*twitch.GlobalUserState)
+	OnNotice          func(*twitch.Notice)
+	OnJoin            func(*twitch.Join)
+	OnPart            func(*twitch.Part)
+	OnWhisper         func(*twitch.Whisper)
+
+	Default func(*irc.Message)
+}
+
+func (m *Mux) Connected() {
+	if m.OnConnected != nil {
+		m.OnConnected()
+	}
+}
+
+func (m *Mux) Disconnected(err error) {
+	if m.OnDisconnected != nil {
+		m.OnDisconnected(err)
+	}
+}
+
+func (m *Mux) Message(msg *irc.Message) {
+	if !m.dispatch(msg) && m.Default != nil {
+		m.Default(msg)
+	}
+}
+
+// dispatch calls the typed callback for msg, reporting whether there was one.
+func (m *Mux) dispatch(msg *irc.Message) bool {
+	switch msg.Command {
+	case "PRIVMSG":
+		if m.OnPrivmsg != nil {
+			m.OnPrivmsg(twitch.ParsePrivmsg(msg))
+			return true
+		}
+	case "USERNOTICE":
+		if m.OnUserNotice != nil {
+			m.OnUserNotice(twitch.ParseUserNotice(msg))
+			return true
+		}
+	case "CLEARCHAT":
+		if m.OnClearChat != nil {
+			m.OnClearChat(twitch.ParseClearChat(msg))
+			return true
+		}
+	case "CLEARMSG":
+		if m.OnClearMsg != nil {
+			m.OnClearMsg(twitch.ParseClearMsg(msg))
+			return true
+		}
+	case "ROOMSTATE":
+		if m.OnRoomState != nil {
+			m.OnRoomState(twitch.ParseRoomState(msg))
+			return true
+		}
+	case "USERSTATE":
+		if m.OnUserState != nil {
+			m.OnUserState(twitch.ParseUserState(msg))
+			return true
+		}
+	case "GLOBALUSERSTATE":
+		if m.OnGlobalUserState != nil {
+			m.OnGlobalUserState(twitch.ParseGlobalUserState(msg))
+			return true
+		}
+	case "NOTICE":
+		if m.OnNotice != nil {
+			m.OnNotice(twitch.ParseNotice(msg))
+			return true
+		}
+	case "JOIN":
+		if m.OnJoin != nil {
+			m.OnJoin(twitch.ParseJoin(msg))
+			return true
+		}
+	case "PART":
+		if m.OnPart != nil {
+			m.OnPart(twitch.ParsePart(msg))
+			return true
+		}
+	case "WHISPER":
+		if m.OnWhisper != nil {
+			m.OnWhisper(twitch.ParseWhisper(msg))
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package ircon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+var muxLines = []string{
+	"@display-name=Alice :alice!alice@alice.tmi.twitch.tv PRIVMSG #chan :hello",
+	"@msg-id=raid :tmi.twitch.tv USERNOTICE #chan",
+	":tmi.twitch.tv CLEARCHAT #chan :bob",
+	"@target-msg-id=m1 :tmi.twitch.tv CLEARMSG #chan :oops",
+	"@slow=10 :tmi.twitch.tv ROOMSTATE #chan",
+	"@mod=1 :tmi.twitch.tv USERSTATE #chan",
+	"@user-id=42 :tmi.twitch.tv GLOBALUSERSTATE",
+	"@msg-id=msg_banned :tmi.twitch.tv NOTICE #chan :You are banned",
+	":carol!carol@carol.tmi.twitch.tv JOIN #chan",
+	":carol!carol@carol.tmi.twitch.tv PART #chan",
+	":dave!dave@dave.tmi.twitch.tv WHISPER me :psst",
+	":tmi.twitch.tv 001 me :Welcome, GLHF!",
+}
+
+func TestMux(t *testing.T) {
+	var got []string
+	add := func(s string) { got = append(got, s) }
+	m := &Mux{
+		OnConnected:       func() { add("connected") },
+		OnDisconnected:    func(err error) { add("disconnected " + err.Error()) },
+		OnPrivmsg:         func(p *twitch.Privmsg) { add("privmsg " + p.Text) },
+		OnUserNotice:      func(n *twitch.UserNotice) { add("usernotice " + n.MsgID) },
+		OnClearChat:       func(c *twitch.ClearChat) { add("clearchat " + c.User) },
+		OnClearMsg:        func(c *twitch.ClearMsg) { add("clearmsg " + c.TargetMsgID) },
+		OnRoomState:       func(r *twitch.RoomState) { add("roomstate " + r.Channel) },
+		OnUserState:       func(u *twitch.UserState) { add("userstate " + u.Channel) },
+		OnGlobalUserState: func(g *twitch.GlobalUserState) { add("globaluserstate " + g.UserID) },
+		OnNotice:          func(n *twitch.Notice) { add("notice " + n.MsgID) },
+		OnJoin:            func(j *twitch.Join) { add("join " + j.User) },
+		OnPart:            func(p *twitch.Part) { add("part " + p.User) },
+		OnWhisper:         func(w *twitch.Whisper) { add("whisper " + w.From) },
+		Default:           func(msg *irc.Message) { add("default " + msg.Command) },
+	}
+	m.Connected()
+	for _, line := range muxLines {
+		m.Message(irc.ParseMessage(line))
+	}
+	m.Disconnected(errors.New("bye"))
+
+	want := []string{
+		"connected",
+		"privmsg hello",
+		"usernotice raid",
+		"clearchat bob",
+		"clearmsg m1",
+		"roomstate chan",
+		"userstate chan",
+		"globaluserstate 42",
+		"notice msg_banned",
+		"join carol",
+		"part carol",
+		"whisper dave",
+		"default 001",
+		"disconnected bye",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Wrong calls:\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestMuxDefault(t *testing.T) {
+	var got []string
+	m := &Mux{Default: func(msg *irc.Message) { got = append(got, msg.Command) }}
+	m.Connected()
+	m.Disconnected(nil)
+	for _, line := range muxLines {
+		m.Message(irc.ParseMessage(line))
+	}
+	want := []string{"PRIVMSG", "USERNOTICE", "CLEARCHAT", "CLEARMSG", "ROOMSTATE", "USERSTATE",
+		"GLOBALUSERSTATE", "NOTICE", "JOIN", "PART", "WHISPER", "001"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Wrong messages: %q", got)
+	}
+
+	// Without Default, unhandled messages are dropped
+	(&Mux{}).Message(irc.ParseMessage(muxLines[0]))
+}
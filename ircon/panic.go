@@ -0,0 +1,83 @@
+package ircon
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"runtime/debug"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A Panic is a panic recovered from a Handler callback.
+type Panic struct {
+	Value    interface{}
+	Callback string       // Connected, Disconnected or Message
+	Message  *irc.Message // The message being handled, for Message
+	Stack    []byte
+}
+
+func (p *Panic) Error() string {
+	return fmt.Sprintf("Handler panic in %s: %v", p.Callback, p.Value)
+}
+
+// guard is a Handler recovering from panics of the Handler it wraps.
+type guard struct {
+	i *IRCon
+	h Handler
+}
+
+func (g guard) Connected() {
+	defer g.recover("Connected", nil)
+	g.h.Connected()
+}
+
+func (g guard) Disconnected(err error) {
+	defer g.recover("Disconnected", nil)
+	g.h.Disconnected(err)
+}
+
+func (g guard) Message(msg *irc.Message) {
+	defer g.recover("Message", msg)
+	g.h.Message(msg)
+}
+
+func (g guard) recover(callback string, msg *irc.Message) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	p := newPanic(v, callback, msg)
+	p.report(g.i.PanicHandler, g.i.Logger)
+
+	if g.i.RestartOnPanic && callback != "Disconnected" {
+		g.i.mu.Lock()
+		c := g.i.con
+		g.i.mu.Unlock()
+		if c != nil {
+			c.closeWithErr(p)
+		}
+	}
+}
+
+// newPanic records a recovered panic. It must be called from the deferred
+// function, for the stack to include the panicking callback.
+func newPanic(v interface{}, callback string, msg *irc.Message) *Panic {
+	return &Panic{
+		Value:    v,
+		Callback: callback,
+		Message:  msg,
+		Stack:    debug.Stack(),
+	}
+}
+
+// report passes p to handler, or logs it if handler is nil.
+func (p *Panic) report(handler func(*Panic), logger *slog.Logger) {
+	if handler != nil {
+		handler(p)
+	} else if logger != nil {
+		logger.Error("Handler panic", "callback", p.Callback, "panic", p.Value, "stack", string(p.Stack))
+	} else {
+		log.Printf("%v\n%s", p, p.Stack)
+	}
+}
@@ -0,0 +1,42 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestPanic(t *testing.T) {
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	i := New(d, TwitchHandshaker("", ""))
+	i.Reconnect = Fixed{Delay: time.Hour}
+	i.RestartOnPanic = true
+	panics := make(chan *Panic, 1)
+	i.PanicHandler = func(p *Panic) { panics <- p }
+
+	r := newRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go i.Run(ctx, &Mux{
+		OnDisconnected: r.Disconnected,
+		Default: func(msg *irc.Message) {
+			panic("boom")
+		},
+	})
+
+	c.in <- ":tmi.twitch.tv NOTICE * :Hi"
+	p := <-panics
+	if p.Callback != "Message" || p.Message.Trailer(1) != "Hi" || p.Value != "boom" || len(p.Stack) == 0 {
+		t.Errorf("Wrong panic: %v", p)
+	}
+	var perr *Panic
+	if err := <-r.disconnected; !errors.As(err, &perr) {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
@@ -0,0 +1,335 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+)
+
+// DefaultJoinLimit and DefaultJoinWindow follow TMI's JOIN rate limit for
+// regular accounts.
+const (
+	DefaultJoinLimit  = 20
+	DefaultJoinWindow = 10 * time.Second
+)
+
+var ErrNotJoined = errors.New("Channel not joined")
+
+// A PoolHandler receives events from all connections of a Pool. Calls identify
+// the connection they originate from.
+type PoolHandler interface {
+	Connected(c *PoolConn)
+	Disconnected(c *PoolConn, err error)
+	Message(c *PoolConn, msg *irc.Message)
+}
+
+// A PoolConn is a connection of a Pool.
+type PoolConn struct {
+	ID int
+	*IRCon
+
+	// healthy is cleared when the connection is lost, and set again when it is
+	// established. ready is only set while connected.
+	healthy  bool
+	ready    bool
+	channels map[string]bool
+}
+
+// A Pool shards channels across multiple IRCons, which all share the same
+// Dialer and Handshaker. Channels of a failed connection are moved to the
+// remaining connections, and moved back by the Balancer when a connection
+// comes up. The pool rejoins channels after reconnects, and limits JOINs
+// across all connections.
+type Pool struct {
+	// Balancer assigns channels to connections. If nil, LeastLoaded is used.
+	Balancer Balancer
+
+	// JoinLimit JOINs are sent per JoinWindow. If zero, DefaultJoinLimit and
+	// DefaultJoinWindow are used.
+	JoinLimit  int
+	JoinWindow time.Duration
+
+	// Clock is used for all timing, including by the connections. If nil,
+	// clock.System is used.
+	Clock clock.Clock
+
+	conns      []*PoolConn
+	mu         sync.Mutex
+	assigned   map[string]*PoolConn
+	unassigned map[string]bool
+	queue      []string
+	queued     map[string]bool
+	wake       chan struct{}
+}
+
+// NewPool creates a Pool of size connections.
+func NewPool(d irc.Dialer, h Handshaker, size int) *Pool {
+	p := &Pool{
+		assigned:   make(map[string]*PoolConn),
+		unassigned: make(map[string]bool),
+		queued:     make(map[string]bool),
+		wake:       make(chan struct{}, 1),
+	}
+	for n := 0; n < size; n++ {
+		p.conns = append(p.conns, &PoolConn{
+			ID:       n,
+			IRCon:    New(d, h),
+			healthy:  true,
+			channels: make(map[string]bool),
+		})
+	}
+	return p
+}
+
+// Run maintains all connections until the context is done.
+func (p *Pool) Run(ctx context.Context, h PoolHandler) {
+	var wg sync.WaitGroup
+	for _, c := range p.conns {
+		if p.Clock != nil {
+			c.Clock = p.Clock
+		}
+		wg.Add(1)
+		go func(c *PoolConn) {
+			defer wg.Done()
+			c.Run(ctx, &poolHandler{p: p, c: c, h: h})
+		}(c)
+	}
+	p.joiner(ctx)
+	wg.Wait()
+}
+
+// Conns returns all connections of the pool.
+func (p *Pool) Conns() []*PoolConn {
+	return p.conns
+}
+
+// Conn returns the connection a channel is assigned to, or nil.
+func (p *Pool) Conn(channel string) *PoolConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.assigned[normalizeChannel(channel)]
+}
+
+// Join assigns channels to connections, and queues them to be joined.
+func (p *Pool) Join(channels ...string) {
+	p.mu.Lock()
+	for _, ch := range channels {
+		ch = normalizeChannel(ch)
+		if p.assigned[ch] != nil {
+			continue
+		}
+		p.assign(ch)
+	}
+	p.mu.Unlock()
+	p.notify()
+}
+
+// Part leaves channels.
+func (p *Pool) Part(channels ...string) {
+	for _, ch := range channels {
+		ch = normalizeChannel(ch)
+		p.mu.Lock()
+		c := p.assigned[ch]
+		delete(p.assigned, ch)
+		delete(p.unassigned, ch)
+		ready := false
+		if c != nil {
+			delete(c.channels, ch)
+			ready = c.ready
+		}
+		p.mu.Unlock()
+		if ready {
+			c.Send("PART #" + ch)
+		}
+	}
+}
+
+// Send sends a message on the connection a channel is assigned to.
+func (p *Pool) Send(channel, s string) error {
+	c := p.Conn(channel)
+	if c == nil {
+		return ErrNotJoined
+	}
+	return c.Send(s)
+}
+
+// assign picks a connection for ch and queues the JOIN. The lock must be held.
+func (p *Pool) assign(ch string) {
+	balancer := p.Balancer
+	if balancer == nil {
+		balancer = LeastLoaded{}
+	}
+	loads := make([]ConnLoad, len(p.conns))
+	for n, c := range p.conns {
+		loads[n] = ConnLoad{ID: c.ID, Healthy: c.healthy, Channels: len(c.channels)}
+	}
+	id := balancer.Pick(ch, loads)
+	if id < 0 || id >= len(p.conns) {
+		p.unassigned[ch] = true
+		return
+	}
+	p.move(ch, p.conns[id])
+}
+
+// move assigns ch to c and queues the JOIN. The lock must be held.
+func (p *Pool) move(ch string, c *PoolConn) {
+	delete(p.unassigned, ch)
+	p.assigned[ch] = c
+	c.channels[ch] = true
+	p.enqueue(ch)
+}
+
+// enqueue queues a JOIN, unless one is queued already. The lock must be held.
+func (p *Pool) enqueue(ch string) {
+	if !p.queued[ch] {
+		p.queued[ch] = true
+		p.queue = append(p.queue, ch)
+	}
+}
+
+// rebalance moves channels from other connections to c where the Balancer
+// picks c, e.g. after all channels were moved to the first connection that
+// came up. It returns the moved channels by their previous connection, to be
+// parted. The lock must be held.
+func (p *Pool) rebalance(c *PoolConn) map[*PoolConn][]string {
+	balancer := p.Balancer
+	if balancer == nil {
+		balancer = LeastLoaded{}
+	}
+	loads := make([]ConnLoad, len(p.conns))
+	for n, o := range p.conns {
+		loads[n] = ConnLoad{ID: o.ID, Healthy: o.healthy, Channels: len(o.channels)}
+	}
+	parts := make(map[*PoolConn][]string)
+	for _, o := range p.conns {
+		if o == c {
+			continue
+		}
+		channels := make([]string, 0, len(o.channels))
+		for ch := range o.channels {
+			channels = append(channels, ch)
+		}
+		sort.Strings(channels)
+		for _, ch := range channels {
+			// Pick as if the channel was not assigned yet
+			loads[o.ID].Channels--
+			if balancer.Pick(ch, loads) != c.ID {
+				loads[o.ID].Channels++
+				continue
+			}
+			loads[c.ID].Channels++
+			delete(o.channels, ch)
+			p.move(ch, c)
+			if o.ready {
+				parts[o] = append(parts[o], ch)
+			}
+		}
+	}
+	return parts
+}
+
+func (p *Pool) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// joiner sends queued JOINs within the rate limit.
+func (p *Pool) joiner(ctx context.Context) {
+	limit, window := p.JoinLimit, p.JoinWindow
+	if limit <= 0 || window <= 0 {
+		limit, window = DefaultJoinLimit, DefaultJoinWindow
+	}
+	clk := p.Clock
+	if clk == nil {
+		clk = clock.System
+	}
+	l := newLimiter(limit, window, clk)
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.wake:
+			}
+			continue
+		}
+		ch := p.queue[0]
+		p.queue = p.queue[1:]
+		delete(p.queued, ch)
+		c := p.assigned[ch]
+		p.mu.Unlock()
+
+		// Connections that are not ready rejoin all channels once connected
+		if c == nil || !p.isReady(c) {
+			continue
+		}
+		if err := l.Wait(ctx); err != nil {
+			return
+		}
+		c.Send("JOIN #" + ch)
+	}
+}
+
+func (p *Pool) isReady(c *PoolConn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return c.ready
+}
+
+type poolHandler struct {
+	p *Pool
+	c *PoolConn
+	h PoolHandler
+}
+
+func (ph *poolHandler) Connected() {
+	p, c := ph.p, ph.c
+	p.mu.Lock()
+	c.healthy, c.ready = true, true
+	for ch := range c.channels {
+		p.enqueue(ch)
+	}
+	for ch := range p.unassigned {
+		p.assign(ch)
+	}
+	parts := p.rebalance(c)
+	p.mu.Unlock()
+	for o, channels := range parts {
+		o.Send("PART #" + strings.Join(channels, ",#"))
+	}
+	p.notify()
+	ph.h.Connected(c)
+}
+
+func (ph *poolHandler) Disconnected(err error) {
+	p, c := ph.p, ph.c
+	p.mu.Lock()
+	c.healthy, c.ready = false, false
+	moved := c.channels
+	c.channels = make(map[string]bool)
+	for ch := range moved {
+		delete(p.assigned, ch)
+		p.assign(ch)
+	}
+	p.mu.Unlock()
+	p.notify()
+	ph.h.Disconnected(c, err)
+}
+
+func (ph *poolHandler) Message(msg *irc.Message) {
+	ph.h.Message(ph.c, msg)
+}
+
+func normalizeChannel(ch string) string {
+	return strings.ToLower(strings.TrimPrefix(ch, "#"))
+}
@@ -0,0 +1,150 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+)
+
+type nopPoolHandler struct{}
+
+func (nopPoolHandler) Connected(*PoolConn)             {}
+func (nopPoolHandler) Disconnected(*PoolConn, error)   {}
+func (nopPoolHandler) Message(*PoolConn, *irc.Message) {}
+
+// loads returns the number of channels of every connection.
+func (p *Pool) loads() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var res []int
+	for _, c := range p.conns {
+		res = append(res, len(c.channels))
+	}
+	return res
+}
+
+func TestPoolQueue(t *testing.T) {
+	p := NewPool(fakeDialer{}, TwitchHandshaker("", ""), 1)
+	ph := &poolHandler{p: p, c: p.conns[0], h: nopPoolHandler{}}
+	p.Join("#a", "b")
+	ph.Connected()
+	if !reflect.DeepEqual(p.queue, []string{"a", "b"}) {
+		t.Errorf("Wrong queue: %q", p.queue)
+	}
+}
+
+func TestPoolRebalance(t *testing.T) {
+	p := NewPool(fakeDialer{}, TwitchHandshaker("", ""), 3)
+	var ph []*poolHandler
+	for _, c := range p.conns {
+		ph = append(ph, &poolHandler{p: p, c: c, h: nopPoolHandler{}})
+	}
+	p.Join("a", "b", "c", "d", "e", "f")
+	if l := p.loads(); !reflect.DeepEqual(l, []int{2, 2, 2}) {
+		t.Errorf("Wrong loads: %v", l)
+	}
+
+	// All connections fail before coming up
+	for _, h := range ph {
+		h.Disconnected(errors.New("Dial failed"))
+	}
+	if l := p.loads(); !reflect.DeepEqual(l, []int{0, 0, 0}) || len(p.unassigned) != 6 {
+		t.Errorf("Wrong loads: %v, %v", l, p.unassigned)
+	}
+
+	ph[1].Connected()
+	if l := p.loads(); !reflect.DeepEqual(l, []int{0, 6, 0}) {
+		t.Errorf("Wrong loads: %v", l)
+	}
+	ph[0].Connected()
+	ph[2].Connected()
+	if l := p.loads(); !reflect.DeepEqual(l, []int{2, 2, 2}) {
+		t.Errorf("Not rebalanced: %v", l)
+	}
+	var channels []string
+	for ch, c := range p.assigned {
+		if !c.channels[ch] {
+			t.Errorf("Inconsistent assignment of %s", ch)
+		}
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	if !reflect.DeepEqual(channels, []string{"a", "b", "c", "d", "e", "f"}) {
+		t.Errorf("Wrong channels: %q", channels)
+	}
+}
+
+func TestPool(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	d := fakeDialer{conns: make(chan *fakeConn, 2)}
+	first := newFakeConn()
+	d.conns <- first
+
+	p := NewPool(d, TwitchHandshaker("", ""), 1)
+	p.Clock = clk
+	p.JoinLimit = 2
+	p.JoinWindow = 10 * time.Second
+	p.conns[0].Reconnect = Fixed{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx, nopPoolHandler{})
+	for p.conns[0].State() != Handshaking && p.conns[0].State() != Registered {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The JOIN limit applies across the pool
+	p.Join("a", "b", "c")
+	first.expect("JOIN #a")
+	first.expect("JOIN #b")
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case line := <-first.out:
+		t.Fatalf("Limit not applied: %q", line)
+	default:
+	}
+	clk.Advance(10 * time.Second)
+	first.expect("JOIN #c")
+	if err := p.Send("#b", "PRIVMSG #b :hi"); err != nil {
+		t.Fatal(err)
+	}
+	first.expect("PRIVMSG #b :hi")
+	if err := p.Send("#x", "PRIVMSG #x :hi"); err != ErrNotJoined {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	// Channels are rejoined after a reconnect
+	second := newFakeConn()
+	d.conns <- second
+	first.Close()
+	joined := make(map[string]bool)
+	for len(joined) < 3 {
+		for clk.Waiters() == 0 && len(second.out) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clk.Advance(10 * time.Second)
+		select {
+		case line := <-second.out:
+			if len(line) > 5 && line[:5] == "JOIN " {
+				joined[line] = true
+			}
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if !joined["JOIN #a"] || !joined["JOIN #b"] || !joined["JOIN #c"] {
+		t.Errorf("Wrong rejoins: %v", joined)
+	}
+
+	p.Part("#b")
+	second.expect("PART #b")
+	if p.Conn("b") != nil {
+		t.Error("Still assigned")
+	}
+}
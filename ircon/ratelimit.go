@@ -0,0 +1,48 @@
+package ircon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+)
+
+// limiter allows n events within every window, e.g. TMI's limit of 20 JOINs
+// per 10 seconds.
+type limiter struct {
+	n      int
+	window time.Duration
+	clock  clock.Clock
+
+	mu   sync.Mutex
+	past []time.Time
+}
+
+func newLimiter(n int, window time.Duration, clk clock.Clock) *limiter {
+	return &limiter{n: n, window: window, clock: clk}
+}
+
+// Wait blocks until another event is allowed, and records it.
+func (l *limiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := l.clock.Now()
+		for len(l.past) > 0 && now.Sub(l.past[0]) >= l.window {
+			l.past = l.past[1:]
+		}
+		if len(l.past) < l.n {
+			l.past = append(l.past, now)
+			l.mu.Unlock()
+			return nil
+		}
+		wait := l.window - now.Sub(l.past[0])
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.clock.After(wait):
+		}
+	}
+}
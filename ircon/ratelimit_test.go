@@ -0,0 +1,33 @@
+package ircon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+)
+
+func TestLimiter(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	l := newLimiter(2, 10*time.Second, clk)
+	ctx := context.Background()
+	l.Wait(ctx)
+	l.Wait(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Wait(ctx)
+	}()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("Limit exceeded")
+	default:
+	}
+	clk.Advance(10 * time.Second)
+	<-done
+}
@@ -0,0 +1,151 @@
+package ircon
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A SessionEnd describes how a session ended, for a ReconnectPolicy.
+type SessionEnd struct {
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+
+	// Connected is set if the server welcomed the connection.
+	Connected bool
+
+	// Clean is set if the server closed the connection in an orderly way,
+	// e.g. after a RECONNECT.
+	Clean bool
+
+	// Attempt counts the consecutive sessions that did not stay connected
+	// for StableSession, including this one.
+	Attempt int
+}
+
+// DefaultFastRetryDelay is the delay of FastRetry if Delay is not set.
+const DefaultFastRetryDelay = time.Second
+
+// StableSession is the time a session needs to stay connected for the attempt
+// count to be reset.
+const StableSession = time.Minute
+
+// A ReconnectPolicy decides when to reconnect after a session ended. Calls are
+// not concurrent.
+type ReconnectPolicy interface {
+	// Next returns the delay before the next session, or false to stop
+	// reconnecting.
+	Next(end SessionEnd) (time.Duration, bool)
+}
+
+// DefaultReconnectPolicy returns the policy used if IRCon.Reconnect is nil: an
+// Adaptive delay between 15 seconds and 5 minutes, with fast retries after
+// clean closes.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return &FastRetry{
+		Policy: &Adaptive{Min: 15 * time.Second, Max: 300 * time.Second},
+	}
+}
+
+// Fixed always waits Delay.
+type Fixed struct {
+	Delay time.Duration
+}
+
+func (f Fixed) Next(SessionEnd) (time.Duration, bool) {
+	return f.Delay, true
+}
+
+// Exponential doubles the maximum delay from Min for every attempt up to Max,
+// and waits a random delay up to it ("full jitter").
+type Exponential struct {
+	Min, Max time.Duration
+
+	// Rand is the source of jitter. If nil, a shared source is used.
+	Rand *rand.Rand
+}
+
+func (e *Exponential) Next(end SessionEnd) (time.Duration, bool) {
+	ceil := e.Min
+	for n := 1; n < end.Attempt && ceil < e.Max; n++ {
+		ceil *= 2
+	}
+	if ceil > e.Max {
+		ceil = e.Max
+	}
+	return randDuration(e.Rand, 0, ceil), true
+}
+
+// DecorrelatedJitter waits a random delay between Min and three times the
+// previous delay, capped at Max.
+type DecorrelatedJitter struct {
+	Min, Max time.Duration
+
+	// Rand is the source of jitter. If nil, a shared source is used.
+	Rand *rand.Rand
+
+	prev time.Duration
+}
+
+func (d *DecorrelatedJitter) Next(end SessionEnd) (time.Duration, bool) {
+	if end.Attempt <= 1 || d.prev < d.Min {
+		d.prev = d.Min
+	}
+	delay := randDuration(d.Rand, d.Min, d.prev*3)
+	if delay > d.Max {
+		delay = d.Max
+	}
+	d.prev = delay
+	return delay, true
+}
+
+// GiveUp stops reconnecting after Attempts consecutive failed sessions, and
+// otherwise defers to Policy.
+type GiveUp struct {
+	Attempts int
+	Policy   ReconnectPolicy
+}
+
+func (g *GiveUp) Next(end SessionEnd) (time.Duration, bool) {
+	if end.Attempt >= g.Attempts {
+		return 0, false
+	}
+	return g.Policy.Next(end)
+}
+
+// FastRetry reconnects after Delay when the server cleanly closed a connection
+// that was stable before, and otherwise defers to Policy. If Delay is zero,
+// DefaultFastRetryDelay is used.
+type FastRetry struct {
+	Delay  time.Duration
+	Policy ReconnectPolicy
+}
+
+func (f *FastRetry) Next(end SessionEnd) (time.Duration, bool) {
+	if !end.Clean || !end.Connected || end.Attempt > 1 {
+		return f.Policy.Next(end)
+	}
+	if f.Delay <= 0 {
+		return DefaultFastRetryDelay, true
+	}
+	return f.Delay, true
+}
+
+var (
+	jitterMu  sync.Mutex
+	jitterSrc = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// randDuration returns a random duration in [min, max].
+func randDuration(r *rand.Rand, min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	if r == nil {
+		jitterMu.Lock()
+		defer jitterMu.Unlock()
+		r = jitterSrc
+	}
+	return min + time.Duration(r.Int63n(int64(max-min)+1))
+}
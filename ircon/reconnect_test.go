@@ -0,0 +1,140 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+)
+
+func TestAdaptive(t *testing.T) {
+	a := &Adaptive{Min: 15 * time.Second, Max: 300 * time.Second}
+	delays := []time.Duration{30, 45, 60}
+	for _, want := range delays {
+		if d, _ := a.Next(SessionEnd{}); d != want*time.Second {
+			t.Errorf("Wrong delay: %v != %v", d, want*time.Second)
+		}
+	}
+	// A long session reduces the delay again
+	if d, _ := a.Next(SessionEnd{Duration: time.Hour}); d != 0 || a.delay != 30*time.Second {
+		t.Errorf("Wrong delay: %v, %v", d, a.delay)
+	}
+}
+
+func TestExponential(t *testing.T) {
+	e := &Exponential{Min: time.Second, Max: 8 * time.Second, Rand: rand.New(rand.NewSource(1))}
+	ceil := []time.Duration{1, 2, 4, 8, 8}
+	for n, c := range ceil {
+		d, ok := e.Next(SessionEnd{Attempt: n + 1})
+		if !ok || d < 0 || d > c*time.Second {
+			t.Errorf("Wrong delay for attempt %d: %v", n+1, d)
+		}
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	d := &DecorrelatedJitter{Min: time.Second, Max: 10 * time.Second, Rand: rand.New(rand.NewSource(1))}
+	for n := 1; n < 20; n++ {
+		delay, _ := d.Next(SessionEnd{Attempt: n})
+		if delay < time.Second || delay > 10*time.Second {
+			t.Errorf("Wrong delay: %v", delay)
+		}
+	}
+}
+
+func TestFastRetryGiveUp(t *testing.T) {
+	p := &GiveUp{
+		Attempts: 3,
+		Policy:   &FastRetry{Policy: Fixed{Delay: time.Minute}},
+	}
+	if d, ok := p.Next(SessionEnd{Attempt: 1, Connected: true, Clean: true}); !ok || d != DefaultFastRetryDelay {
+		t.Errorf("Wrong delay: %v, %v", d, ok)
+	}
+	// Repeated clean closes are not retried fast
+	if d, ok := p.Next(SessionEnd{Attempt: 2, Connected: true, Clean: true}); !ok || d != time.Minute {
+		t.Errorf("Wrong delay: %v, %v", d, ok)
+	}
+	if _, ok := p.Next(SessionEnd{Attempt: 3}); ok {
+		t.Error("Should give up")
+	}
+}
+
+type failDialer struct {
+	dials int32
+}
+
+func (d *failDialer) Dial(context.Context) (irc.Conn, error) {
+	atomic.AddInt32(&d.dials, 1)
+	return nil, errors.New("Dial failed")
+}
+
+func TestReconnectClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	d := &failDialer{}
+	i := New(d, TwitchHandshaker("", ""))
+	i.Clock = clk
+	i.Reconnect = &GiveUp{Attempts: 3, Policy: Fixed{Delay: time.Minute}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		i.Run(context.Background(), &Mux{})
+	}()
+
+	for n := int32(1); n < 3; n++ {
+		for clk.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if dials := atomic.LoadInt32(&d.dials); dials != n {
+			t.Fatalf("Wrong dials: %d != %d", dials, n)
+		}
+		clk.Advance(time.Minute)
+	}
+	<-done
+	if dials := atomic.LoadInt32(&d.dials); dials != 3 {
+		t.Errorf("Wrong dials: %d", dials)
+	}
+}
+
+type policyFunc func(SessionEnd) (time.Duration, bool)
+
+func (f policyFunc) Next(end SessionEnd) (time.Duration, bool) { return f(end) }
+
+func TestSessionEnd(t *testing.T) {
+	d := fakeDialer{conns: make(chan *fakeConn, 2)}
+	first, second := newFakeConn(), newFakeConn()
+	d.conns <- first
+	d.conns <- second
+
+	i := New(d, TwitchHandshaker("bot", "oauth:x"))
+	var ends []SessionEnd
+	i.Reconnect = policyFunc(func(end SessionEnd) (time.Duration, bool) {
+		ends = append(ends, end)
+		return 0, len(ends) < 2
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		i.Run(context.Background(), newRecorder())
+	}()
+
+	// A connection closed before 001 does not count as connected
+	first.expect("NICK bot")
+	first.Close()
+	second.expect("NICK bot")
+	second.in <- ":tmi.twitch.tv 001 bot :Welcome, GLHF!"
+	if err := i.WaitFor(context.Background(), Registered); err != nil {
+		t.Fatal(err)
+	}
+	second.Close()
+	<-done
+
+	if len(ends) != 2 || ends[0].Connected || !ends[1].Connected || !ends[1].Clean || ends[1].Attempt != 2 {
+		t.Errorf("Wrong session ends: %+v", ends)
+	}
+}
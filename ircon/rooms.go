@@ -0,0 +1,158 @@
+package ircon
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+// Errors returned when the room state would reject a message. These match the
+// NOTICEs TMI responds with.
+var (
+	ErrSubsOnly      = twitch.ErrSubsOnly
+	ErrFollowersOnly = twitch.ErrFollowersOnly
+)
+
+// A RoomTracker merges ROOMSTATE messages into the current state of every
+// joined channel. The zero value is ready to use.
+type RoomTracker struct {
+	// OnChange is called after the state of a channel changed. It should be set
+	// before the tracker receives messages.
+	OnChange func(old, new twitch.RoomState)
+
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+type room struct {
+	state twitch.RoomState
+
+	// lastSent is the time of the last PRIVMSG, for slow mode
+	lastSent time.Time
+	// followersRejected is set when TMI rejected a message due to
+	// followers-only mode, as the follow status is not known otherwise.
+	followersRejected bool
+}
+
+// Room returns the state of a channel.
+func (t *RoomTracker) Room(channel string) (twitch.RoomState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.rooms[channel]
+	if !ok {
+		return twitch.RoomState{}, false
+	}
+	return r.state, true
+}
+
+// Rooms returns the state of all known channels.
+func (t *RoomTracker) Rooms() []twitch.RoomState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := make([]twitch.RoomState, 0, len(t.rooms))
+	for _, r := range t.rooms {
+		res = append(res, r.state)
+	}
+	return res
+}
+
+// Message updates the tracker from an incoming message.
+func (t *RoomTracker) Message(msg *irc.Message) {
+	switch msg.Command {
+	case "ROOMSTATE":
+		t.mu.Lock()
+		r := t.room(strings.TrimPrefix(msg.Arg(0), "#"))
+		old := r.state
+		r.state.Apply(msg)
+		if old.FollowersOnly != r.state.FollowersOnly || old.FollowersMin != r.state.FollowersMin {
+			r.followersRejected = false
+		}
+		state := r.state
+		t.mu.Unlock()
+		if t.OnChange != nil {
+			t.OnChange(old, state)
+		}
+	case "NOTICE":
+		n := twitch.ParseNotice(msg)
+		if strings.HasPrefix(n.MsgID, "msg_followersonly") {
+			t.mu.Lock()
+			t.room(n.Channel).followersRejected = true
+			t.mu.Unlock()
+		}
+	}
+}
+
+// Reset forgets all channels, e.g. when the connection is lost.
+func (t *RoomTracker) Reset() {
+	t.mu.Lock()
+	t.rooms = nil
+	t.mu.Unlock()
+}
+
+func (t *RoomTracker) room(channel string) *room {
+	if t.rooms == nil {
+		t.rooms = make(map[string]*room)
+	}
+	r := t.rooms[channel]
+	if r == nil {
+		r = &room{}
+		t.rooms[channel] = r
+	}
+	return r
+}
+
+// admit checks whether an outgoing message would be accepted in the current
+// room state and the user's state in the room. It waits until slow mode allows
+// another message, and reserves the slow mode slot. If the message is not sent
+// after all, undo must be called to release the slot.
+func (t *RoomTracker) admit(ctx context.Context, line string, self *SelfTracker, clk clock.Clock) (undo func(), err error) {
+	msg := irc.ParseMessage(line)
+	if msg.Command != "PRIVMSG" || !strings.HasPrefix(msg.Arg(0), "#") {
+		return func() {}, nil
+	}
+	channel := msg.Arg(0)[1:]
+	us, _ := self.Channel(channel)
+	exempt := us.Moderator() || us.VIP()
+	for {
+		t.mu.Lock()
+		r, ok := t.rooms[channel]
+		if !ok {
+			t.mu.Unlock()
+			return func() {}, nil
+		}
+		if r.state.SubsOnly && !exempt && !us.Subscriber() {
+			t.mu.Unlock()
+			return nil, ErrSubsOnly
+		}
+		if r.state.FollowersOnly && !exempt && r.followersRejected {
+			t.mu.Unlock()
+			return nil, ErrFollowersOnly
+		}
+		now := clk.Now()
+		wait := r.lastSent.Add(r.state.Slow).Sub(now)
+		if exempt || wait <= 0 {
+			prev := r.lastSent
+			r.lastSent = now
+			t.mu.Unlock()
+			return func() {
+				t.mu.Lock()
+				if r.lastSent.Equal(now) {
+					r.lastSent = prev
+				}
+				t.mu.Unlock()
+			}, nil
+		}
+		t.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-clk.After(wait):
+		}
+	}
+}
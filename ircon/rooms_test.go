@@ -0,0 +1,128 @@
+package ircon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+func TestRoomTrackerOnChange(t *testing.T) {
+	var changes []string
+	var r RoomTracker
+	r.OnChange = func(old, new twitch.RoomState) {
+		changes = append(changes, new.Channel+" "+old.Slow.String()+" -> "+new.Slow.String())
+	}
+	r.Message(irc.ParseMessage("@emote-only=0;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #chan"))
+	r.Message(irc.ParseMessage("@slow=30 :tmi.twitch.tv ROOMSTATE #chan"))
+	if len(changes) != 2 || changes[1] != "chan 0s -> 30s" {
+		t.Errorf("Wrong changes: %q", changes)
+	}
+	if s, ok := r.Room("chan"); !ok || s.Slow != 30*time.Second {
+		t.Errorf("Wrong state: %+v", s)
+	}
+	r.Reset()
+	if rooms := r.Rooms(); len(rooms) != 0 {
+		t.Errorf("Not reset: %+v", rooms)
+	}
+}
+
+func TestRoomTrackerAdmit(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	var (
+		r    RoomTracker
+		self SelfTracker
+	)
+	r.Message(irc.ParseMessage("@slow=10;subs-only=0 :tmi.twitch.tv ROOMSTATE #chan"))
+
+	if _, err := r.admit(ctx, "PRIVMSG #chan :one", &self, clk); err != nil {
+		t.Fatal(err)
+	}
+	admitted := make(chan error)
+	go func() {
+		_, err := r.admit(ctx, "PRIVMSG #chan :two", &self, clk)
+		admitted <- err
+	}()
+	for clk.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-admitted:
+		t.Fatal("Slow mode not respected")
+	default:
+	}
+	clk.Advance(10 * time.Second)
+	if err := <-admitted; err != nil {
+		t.Fatal(err)
+	}
+
+	// A released slot is available at once
+	undo, _ := r.admit(ctx, "PRIVMSG #other :not tracked", &self, clk)
+	undo()
+	clk.Advance(10 * time.Second)
+	undo, _ = r.admit(ctx, "PRIVMSG #chan :three", &self, clk)
+	undo()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := r.admit(cancelled, "PRIVMSG #chan :four", &self, clk); err != nil {
+		t.Errorf("Slot not released: %v", err)
+	}
+
+	// Moderators are exempt from slow mode
+	self.Message(irc.ParseMessage("@badges=moderator/1;mod=1 :tmi.twitch.tv USERSTATE #chan"))
+	if _, err := r.admit(cancelled, "PRIVMSG #chan :five", &self, clk); err != nil {
+		t.Errorf("Moderator not exempt: %v", err)
+	}
+}
+
+func TestRoomTrackerRefuse(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Unix(0, 0))
+	var (
+		r    RoomTracker
+		self SelfTracker
+	)
+	r.Message(irc.ParseMessage("@subs-only=1 :tmi.twitch.tv ROOMSTATE #subs"))
+	r.Message(irc.ParseMessage("@followers-only=10 :tmi.twitch.tv ROOMSTATE #follows"))
+
+	if _, err := r.admit(ctx, "PRIVMSG #subs :hi", &self, clk); err != ErrSubsOnly {
+		t.Errorf("Wrong error: %v", err)
+	}
+	self.Message(irc.ParseMessage("@badges=subscriber/3 :tmi.twitch.tv USERSTATE #subs"))
+	if _, err := r.admit(ctx, "PRIVMSG #subs :hi", &self, clk); err != nil {
+		t.Errorf("Subscriber refused: %v", err)
+	}
+
+	// The follow status is only known once TMI rejected a message
+	if _, err := r.admit(ctx, "PRIVMSG #follows :hi", &self, clk); err != nil {
+		t.Errorf("Refused early: %v", err)
+	}
+	r.Message(irc.ParseMessage("@msg-id=msg_followersonly :tmi.twitch.tv NOTICE #follows :This room is in followers-only mode."))
+	if _, err := r.admit(ctx, "PRIVMSG #follows :hi", &self, clk); err != ErrFollowersOnly {
+		t.Errorf("Wrong error: %v", err)
+	}
+	r.Message(irc.ParseMessage("@followers-only=-1 :tmi.twitch.tv ROOMSTATE #follows"))
+	if _, err := r.admit(ctx, "PRIVMSG #follows :hi", &self, clk); err != nil {
+		t.Errorf("Refused after mode change: %v", err)
+	}
+}
+
+func TestFailedSendKeepsSlot(t *testing.T) {
+	i := New(fakeDialer{}, TwitchHandshaker("", ""))
+	i.Clock = clock.NewFake(time.Unix(0, 0))
+	i.EnforceRoomState = true
+	i.Rooms.Message(irc.ParseMessage("@slow=30 :tmi.twitch.tv ROOMSTATE #chan"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for n := 0; n < 2; n++ {
+		// Waiting for slow mode would fail with the cancelled context
+		if err := i.SendContext(ctx, "PRIVMSG #chan :hi"); err != ErrNotConnected {
+			t.Errorf("Wrong error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package ircon
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A SASL mechanism authenticates an IRCHandshake during CAP negotiation.
+type SASL interface {
+	Mechanism() string
+	Payload() []byte
+}
+
+// SASLPlain authenticates with a username and password.
+type SASLPlain struct {
+	Authzid  string // Optional identity to act as
+	User     string
+	Password irc.Secret
+}
+
+func (SASLPlain) Mechanism() string { return "PLAIN" }
+
+func (p SASLPlain) Payload() []byte {
+	return []byte(p.Authzid + "\x00" + p.User + "\x00" + string(p.Password))
+}
+
+// SASLExternal authenticates with the TLS client certificate, which is set
+// with irc.TLSConfig.
+type SASLExternal struct {
+	Authzid string // Optional identity to act as
+}
+
+func (SASLExternal) Mechanism() string { return "EXTERNAL" }
+
+func (e SASLExternal) Payload() []byte {
+	return []byte(e.Authzid)
+}
+
+var (
+	// ErrSASLUnavailable is returned when the server rejects the sasl
+	// capability.
+	ErrSASLUnavailable = errors.New("SASL not supported by server")
+	// ErrSASLConversation is returned by Handshake, as SASL needs to read
+	// the server's responses.
+	ErrSASLConversation = errors.New("SASL requires Converse")
+)
+
+// A SASLError is a failed SASL authentication.
+type SASLError struct {
+	Numeric string // e.g. 904 for ERR_SASLFAIL
+	Text    string
+}
+
+func (e *SASLError) Error() string {
+	return "SASL authentication failed (" + e.Numeric + "): " + e.Text
+}
+
+// saslChunk is the maximum length of an AUTHENTICATE argument.
+const saslChunk = 400
+
+// sasl performs the registration with SASL authentication.
+func (h IRCHandshake) sasl(con Sender, msgs <-chan *irc.Message) error {
+	if err := con.Send("CAP REQ :" + strings.TrimSpace(h.Caps+" sasl")); err != nil {
+		return err
+	}
+	if err := h.register(con); err != nil {
+		return err
+	}
+
+	for acked := false; !acked; {
+		msg, ok := <-msgs
+		if !ok {
+			return ErrNotConnected
+		}
+		if msg.Command != "CAP" {
+			continue
+		}
+		switch msg.Arg(1) {
+		case "ACK":
+			acked = true
+		case "NAK":
+			return ErrSASLUnavailable
+		}
+	}
+
+	if err := con.Send("AUTHENTICATE " + h.SASL.Mechanism()); err != nil {
+		return err
+	}
+	for {
+		msg, ok := <-msgs
+		if !ok {
+			return ErrNotConnected
+		}
+		switch msg.Command {
+		case "AUTHENTICATE":
+			if msg.Arg(0) != "+" {
+				continue
+			}
+			if err := authenticate(con, h.SASL.Payload()); err != nil {
+				return err
+			}
+		case "903":
+			return con.Send("CAP END")
+		case "902", "904", "905", "906", "907":
+			return &SASLError{Numeric: msg.Command, Text: msg.Trailer(len(msg.Args) - 1)}
+		}
+	}
+}
+
+// authenticate sends a payload in chunks. A final "+" marks the end if the
+// last chunk is full, or the payload is empty.
+func authenticate(con Sender, payload []byte) error {
+	enc := base64.StdEncoding.EncodeToString(payload)
+	for len(enc) >= saslChunk {
+		if err := con.Send("AUTHENTICATE " + enc[:saslChunk]); err != nil {
+			return err
+		}
+		enc = enc[saslChunk:]
+	}
+	if enc == "" {
+		enc = "+"
+	}
+	return con.Send("AUTHENTICATE " + enc)
+}
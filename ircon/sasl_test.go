@@ -0,0 +1,139 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"raccatta.cc/tmi/irc"
+)
+
+type lines []string
+
+func (l *lines) Send(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func replies(raw ...string) <-chan *irc.Message {
+	msgs := make(chan *irc.Message, len(raw))
+	for _, line := range raw {
+		msgs <- irc.ParseMessage(line)
+	}
+	close(msgs)
+	return msgs
+}
+
+func TestSASLPlain(t *testing.T) {
+	h := IRCHandshake{
+		Nick:  "someone",
+		Ident: "someone",
+		GECOS: "Someone",
+		SASL:  SASLPlain{User: "someone", Password: "hunter2"},
+	}
+	var sent lines
+	err := h.Converse(&sent, replies(
+		":irc.example.net NOTICE * :*** Looking up your hostname",
+		":irc.example.net CAP * ACK :sasl",
+		"AUTHENTICATE +",
+		":irc.example.net 900 someone someone!someone@host someone :You are now logged in as someone",
+		":irc.example.net 903 someone :SASL authentication successful",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := lines{
+		"CAP REQ :sasl",
+		"NICK someone",
+		"USER someone 8 * :Someone",
+		"AUTHENTICATE PLAIN",
+		"AUTHENTICATE AHNvbWVvbmUAaHVudGVyMg==",
+		"CAP END",
+	}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("Wrong lines: %q", sent)
+	}
+	if err := h.Handshake(&sent); err != ErrSASLConversation {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestSASLChunks(t *testing.T) {
+	// 300 bytes encode to exactly 400 characters, ended by "+"
+	var sent lines
+	if err := authenticate(&sent, make([]byte, 300)); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || len(sent[0]) != len("AUTHENTICATE ")+400 || sent[1] != "AUTHENTICATE +" {
+		t.Errorf("Wrong chunks: %q", sent)
+	}
+
+	sent = nil
+	authenticate(&sent, make([]byte, 301))
+	if len(sent) != 2 || sent[1] != "AUTHENTICATE AA==" {
+		t.Errorf("Wrong chunks: %q", sent)
+	}
+
+	sent = nil
+	authenticate(&sent, nil)
+	if !reflect.DeepEqual(sent, lines{"AUTHENTICATE +"}) {
+		t.Errorf("Wrong chunks: %q", sent)
+	}
+}
+
+func TestSASLFailure(t *testing.T) {
+	h := IRCHandshake{Caps: "multi-prefix", Nick: "someone", SASL: SASLExternal{}}
+	var sent lines
+	err := h.Converse(&sent, replies(
+		":irc.example.net CAP * ACK :multi-prefix sasl",
+		"AUTHENTICATE +",
+		":irc.example.net 904 someone :SASL authentication failed",
+	))
+	var serr *SASLError
+	if !errors.As(err, &serr) || serr.Numeric != "904" || serr.Text != "SASL authentication failed" {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if sent[0] != "CAP REQ :multi-prefix sasl" || sent[len(sent)-1] != "AUTHENTICATE +" {
+		t.Errorf("Wrong lines: %q", sent)
+	}
+
+	err = h.Converse(&sent, replies(":irc.example.net CAP * NAK :multi-prefix sasl"))
+	if !errors.Is(err, ErrSASLUnavailable) {
+		t.Errorf("Wrong error: %v", err)
+	}
+	err = h.Converse(&sent, replies())
+	if !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if s := irc.RedactLine("AUTHENTICATE AHNvbWVvbmUAaHVudGVyMg=="); strings.Contains(s, "AHN") {
+		t.Errorf("Payload not redacted: %s", s)
+	}
+}
+
+func TestSASLSession(t *testing.T) {
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	i := New(d, IRCHandshake{Nick: "someone", SASL: SASLExternal{}})
+	r := newRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go i.Run(ctx, r)
+
+	c.expect("CAP REQ :sasl")
+	c.in <- ":irc.example.net CAP * ACK :sasl"
+	c.expect("AUTHENTICATE EXTERNAL")
+	c.in <- "AUTHENTICATE +"
+	c.expect("AUTHENTICATE +")
+	c.in <- ":irc.example.net 903 someone :SASL authentication successful"
+	c.expect("CAP END")
+	<-r.connected
+
+	// Handshakes without SASL do not read the server's responses
+	if TwitchHandshaker("someone", "oauth:x").Converses() {
+		t.Error("Conversation without SASL")
+	}
+}
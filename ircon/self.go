@@ -0,0 +1,101 @@
+package ircon
+
+import (
+	"strings"
+	"sync"
+
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+// SelfState describes the connected user, globally and per joined channel.
+type SelfState struct {
+	Nick     string
+	Global   twitch.GlobalUserState
+	Channels map[string]twitch.UserState
+}
+
+// A SelfTracker maintains the SelfState from GLOBALUSERSTATE and USERSTATE
+// messages. The zero value is ready to use.
+type SelfTracker struct {
+	mu       sync.Mutex
+	nick     string
+	global   twitch.GlobalUserState
+	channels map[string]twitch.UserState
+}
+
+// Global returns the global state of the connected user. It is empty for
+// anonymous connections.
+func (t *SelfTracker) Global() twitch.GlobalUserState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.global
+}
+
+// Nick returns the nickname the server registered the connection with.
+func (t *SelfTracker) Nick() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.nick
+}
+
+// Channel returns the state of the connected user in a channel.
+func (t *SelfTracker) Channel(channel string) (twitch.UserState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.channels[channel]
+	return s, ok
+}
+
+// State returns a snapshot of the complete state.
+func (t *SelfTracker) State() SelfState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := SelfState{
+		Nick:     t.nick,
+		Global:   t.global,
+		Channels: make(map[string]twitch.UserState, len(t.channels)),
+	}
+	for k, v := range t.channels {
+		s.Channels[k] = v
+	}
+	return s
+}
+
+// Message updates the tracker from an incoming message.
+func (t *SelfTracker) Message(msg *irc.Message) {
+	switch msg.Command {
+	case "001":
+		t.mu.Lock()
+		t.nick = msg.Arg(0)
+		t.mu.Unlock()
+	case "GLOBALUSERSTATE":
+		s := twitch.ParseGlobalUserState(msg)
+		t.mu.Lock()
+		t.global = *s
+		t.mu.Unlock()
+	case "USERSTATE":
+		s := twitch.ParseUserState(msg)
+		t.mu.Lock()
+		if t.channels == nil {
+			t.channels = make(map[string]twitch.UserState)
+		}
+		t.channels[s.Channel] = *s
+		t.mu.Unlock()
+	case "PART":
+		t.mu.Lock()
+		if t.nick != "" && strings.HasPrefix(msg.Source, t.nick+"!") {
+			delete(t.channels, strings.TrimPrefix(msg.Arg(0), "#"))
+		}
+		t.mu.Unlock()
+	}
+}
+
+// Reset forgets all state, e.g. when the connection is lost.
+func (t *SelfTracker) Reset() {
+	t.mu.Lock()
+	t.nick = ""
+	t.global = twitch.GlobalUserState{}
+	t.channels = nil
+	t.mu.Unlock()
+}
@@ -0,0 +1,69 @@
+package ircon
+
+import (
+	"strings"
+	"testing"
+
+	"raccatta.cc/tmi/irc"
+	"raccatta.cc/tmi/twitch"
+)
+
+func TestSelfTracker(t *testing.T) {
+	var s SelfTracker
+	for _, test := range []struct {
+		line     string
+		nick     string
+		userID   string
+		channels string // Channels with the user's roles, e.g. "a:msv"
+	}{
+		{":tmi.twitch.tv 001 me :Welcome, GLHF!", "me", "", ""},
+		{"@user-id=42;display-name=Me :tmi.twitch.tv GLOBALUSERSTATE", "me", "42", ""},
+		{"@badges=moderator/1;mod=1 :tmi.twitch.tv USERSTATE #a", "me", "42", "a:m"},
+		{"@badges=vip/1,subscriber/12 :tmi.twitch.tv USERSTATE #b", "me", "42", "a:m b:sv"},
+		{"@badges=broadcaster/1 :tmi.twitch.tv USERSTATE #me", "me", "42", "a:m b:sv me:m"},
+		{"@badges= :tmi.twitch.tv USERSTATE #a", "me", "42", "a: b:sv me:m"},
+		// Others leaving do not matter
+		{":other!other@other.tmi.twitch.tv PART #b", "me", "42", "a: b:sv me:m"},
+		{":me!me@me.tmi.twitch.tv PART #b", "me", "42", "a: me:m"},
+	} {
+		s.Message(irc.ParseMessage(test.line))
+		state := s.State()
+		if state.Nick != test.nick || state.Global.UserID != test.userID || s.Nick() != test.nick {
+			t.Errorf("%s: Wrong state: %+v", test.line, state)
+		}
+		if got := roles(&s, state.Channels); got != test.channels {
+			t.Errorf("%s: Wrong channels: %q != %q", test.line, got, test.channels)
+		}
+	}
+
+	s.Reset()
+	if state := s.State(); state.Nick != "" || state.Global.UserID != "" || len(state.Channels) != 0 {
+		t.Errorf("Not reset: %+v", state)
+	}
+}
+
+// roles formats the channels of a SelfState, checking Channel agrees.
+func roles(s *SelfTracker, channels map[string]twitch.UserState) string {
+	var out []string
+	for _, ch := range []string{"a", "b", "me"} {
+		us, ok := channels[ch]
+		if us2, ok2 := s.Channel(ch); ok != ok2 || us2.Channel != us.Channel {
+			return "Channel disagrees for " + ch
+		}
+		if !ok {
+			continue
+		}
+		r := ch + ":"
+		if us.Moderator() {
+			r += "m"
+		}
+		if us.Subscriber() {
+			r += "s"
+		}
+		if us.VIP() {
+			r += "v"
+		}
+		out = append(out, r)
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,115 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrShutdown is returned by Send after Shutdown was called, and passed to
+// Disconnected when Shutdown closes the connection.
+var ErrShutdown = errors.New("Shut down")
+
+// ErrNotRunning is returned by Shutdown if Run was not called.
+var ErrNotRunning = errors.New("Not running")
+
+// maxLine is the maximum length of an IRC line, without the line ending.
+const maxLine = 510
+
+// Shutdown gracefully stops Run. It refuses new sends, waits for in-flight
+// sends to complete, optionally PARTs the joined channels and sends QUIT, and
+// closes the connection. If ctx is done first, the connection is closed
+// immediately and ctx.Err() is returned.
+//
+// Shutdown returns once Run returned, so no Handler callbacks are running. It
+// returns ErrNotRunning if Run was not called yet.
+func (i *IRCon) Shutdown(ctx context.Context) error {
+	i.mu.Lock()
+	cancel, stopped := i.cancel, i.stopped
+	if stopped == nil {
+		i.mu.Unlock()
+		return ErrNotRunning
+	}
+	i.closing = true
+	i.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		i.sends.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+		err = i.quit()
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	cancel()
+	<-stopped
+	return err
+}
+
+// quit ends the current session, if any.
+func (i *IRCon) quit() error {
+	i.mu.Lock()
+	c := i.con
+	i.mu.Unlock()
+	if c == nil {
+		return nil
+	}
+
+	var err error
+	if i.PartOnShutdown {
+		var channels []string
+		for _, r := range i.Rooms.Rooms() {
+			channels = append(channels, "#"+r.Channel)
+		}
+		sort.Strings(channels)
+		for _, line := range joinLines("PART ", channels) {
+			if err = c.Send(line); err != nil {
+				break
+			}
+		}
+	}
+	if i.QuitOnShutdown && err == nil {
+		err = c.Send("QUIT")
+	}
+	c.closeWithErr(ErrShutdown)
+	return err
+}
+
+// joinLines joins args with commas after prefix, into as many lines as needed
+// to stay within maxLine.
+func joinLines(prefix string, args []string) []string {
+	var lines []string
+	var b strings.Builder
+	for _, arg := range args {
+		if b.Len() > 0 && b.Len()+1+len(arg) > maxLine {
+			lines = append(lines, b.String())
+			b.Reset()
+		}
+		if b.Len() == 0 {
+			b.WriteString(prefix)
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(arg)
+	}
+	if b.Len() > 0 {
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+// beginSend registers an in-flight send, unless Shutdown was called.
+func (i *IRCon) beginSend() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closing {
+		return ErrShutdown
+	}
+	i.sends.Add(1)
+	return nil
+}
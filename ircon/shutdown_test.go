@@ -0,0 +1,87 @@
+package ircon
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	i := New(d, TwitchHandshaker("", ""))
+	i.PartOnShutdown = true
+	i.QuitOnShutdown = true
+	r := newRecorder()
+	done := make(chan struct{})
+	go func() {
+		i.Run(context.Background(), r)
+		close(done)
+	}()
+	<-r.connected
+	c.in <- ":tmi.twitch.tv ROOMSTATE #foo"
+	c.in <- ":tmi.twitch.tv ROOMSTATE #bar"
+	for len(i.Rooms.Rooms()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := i.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	default:
+		t.Error("Run still running")
+	}
+	if line := c.expect("PART "); line != "PART #bar,#foo" {
+		t.Errorf("Wrong PART: %q", line)
+	}
+	c.expect("QUIT")
+	if err := <-r.disconnected; !errors.Is(err, ErrShutdown) {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if err := i.Send("PRIVMSG #foo :hi"); !errors.Is(err, ErrShutdown) {
+		t.Errorf("Send after Shutdown: %v", err)
+	}
+	if s := i.State(); s != Stopped {
+		t.Errorf("Wrong state: %v", s)
+	}
+}
+
+func TestShutdownBeforeRun(t *testing.T) {
+	i := New(fakeDialer{}, TwitchHandshaker("", ""))
+	if err := i.Shutdown(context.Background()); err != ErrNotRunning {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestJoinLines(t *testing.T) {
+	var channels []string
+	for n := 0; n < 100; n++ {
+		channels = append(channels, "#channel_"+strconv.Itoa(n))
+	}
+	lines := joinLines("PART ", channels)
+	if len(lines) < 2 {
+		t.Fatalf("Not split: %d lines", len(lines))
+	}
+	var parted []string
+	for _, line := range lines {
+		if len(line) > maxLine {
+			t.Errorf("Line too long: %d", len(line))
+		}
+		parted = append(parted, strings.Split(strings.TrimPrefix(line, "PART "), ",")...)
+	}
+	if strings.Join(parted, ",") != strings.Join(channels, ",") {
+		t.Errorf("Wrong channels: %v", parted)
+	}
+	if lines := joinLines("PART ", nil); len(lines) != 0 {
+		t.Errorf("Wrong lines: %v", lines)
+	}
+}
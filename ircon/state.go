@@ -0,0 +1,264 @@
+package ircon
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A State is the connection state of an IRCon.
+type State int
+
+const (
+	// Stopped is the state before Run and after it returned.
+	Stopped State = iota
+	Dialing
+	Handshaking
+	// Registered is entered when the server welcomed the connection.
+	Registered
+	// Joining is entered when a JOIN is sent, until all JOINs are confirmed.
+	Joining
+	// BackingOff is the delay between sessions.
+	BackingOff
+)
+
+func (s State) String() string {
+	switch s {
+	case Stopped:
+		return "stopped"
+	case Dialing:
+		return "dialing"
+	case Handshaking:
+		return "handshaking"
+	case Registered:
+		return "registered"
+	case Joining:
+		return "joining"
+	case BackingOff:
+		return "backing off"
+	}
+	return "unknown"
+}
+
+// A Transition is a change of State.
+type Transition struct {
+	From, To State
+	Time     time.Time
+
+	// Cause is the error that ended the session, when entering BackingOff
+	// or Stopped.
+	Cause error
+}
+
+// DefaultJoinTimeout is the time to wait for a JOIN to be answered if
+// JoinTimeout is not set.
+const DefaultJoinTimeout = 10 * time.Second
+
+type stateMachine struct {
+	mu      sync.Mutex
+	state   State
+	changed chan struct{}
+	subs    map[int]func(Transition)
+	nextSub int
+
+	// joins holds the channels with a pending JOIN, by the number of the
+	// JOIN line awaited.
+	joins    map[string]uint64
+	joinLine uint64
+}
+
+// State returns the current connection state.
+func (i *IRCon) State() State {
+	i.sm.mu.Lock()
+	defer i.sm.mu.Unlock()
+	return i.sm.state
+}
+
+// Subscribe calls fn for every state transition, until cancel is called. Calls
+// are made synchronously from the connection's goroutines and should not
+// block.
+func (i *IRCon) Subscribe(fn func(Transition)) (cancel func()) {
+	sm := &i.sm
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.subs == nil {
+		sm.subs = make(map[int]func(Transition))
+	}
+	id := sm.nextSub
+	sm.nextSub++
+	sm.subs[id] = fn
+	return func() {
+		sm.mu.Lock()
+		delete(sm.subs, id)
+		sm.mu.Unlock()
+	}
+}
+
+// WaitFor waits until the connection is in the given state.
+func (i *IRCon) WaitFor(ctx context.Context, s State) error {
+	for {
+		i.sm.mu.Lock()
+		if i.sm.state == s {
+			i.sm.mu.Unlock()
+			return nil
+		}
+		if i.sm.changed == nil {
+			i.sm.changed = make(chan struct{})
+		}
+		changed := i.sm.changed
+		i.sm.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (i *IRCon) setState(to State, cause error) {
+	i.setStateIf(nil, to, cause)
+}
+
+// setStateIf changes the state if cond, which is called with the lock held,
+// returns true.
+func (i *IRCon) setStateIf(cond func() bool, to State, cause error) {
+	sm := &i.sm
+	sm.mu.Lock()
+	if cond != nil && !cond() {
+		sm.mu.Unlock()
+		return
+	}
+	if to != Joining && to != Registered {
+		sm.joins = nil
+	}
+	from := sm.state
+	if from == to {
+		sm.mu.Unlock()
+		return
+	}
+	sm.state = to
+	if sm.changed != nil {
+		close(sm.changed)
+		sm.changed = nil
+	}
+	subs := make([]func(Transition), 0, len(sm.subs))
+	for _, fn := range sm.subs {
+		subs = append(subs, fn)
+	}
+	sm.mu.Unlock()
+
+	t := Transition{From: from, To: to, Time: i.clock().Now(), Cause: cause}
+	if cause != nil {
+		i.log().Info("State changed", "from", from, "to", to, "cause", cause)
+	} else {
+		i.log().Info("State changed", "from", from, "to", to)
+	}
+	for _, fn := range subs {
+		fn(t)
+	}
+}
+
+// sentJoin records the channels of an outgoing JOIN, until they are joined or
+// JoinTimeout expires. JOINs sent before 001 enter Joining once welcomed.
+func (i *IRCon) sentJoin(line string) {
+	msg := irc.ParseMessage(line)
+	sm := &i.sm
+	sm.mu.Lock()
+	state := sm.state
+	if state != Handshaking && state != Registered && state != Joining {
+		sm.mu.Unlock()
+		return
+	}
+	if sm.joins == nil {
+		sm.joins = make(map[string]uint64)
+	}
+	sm.joinLine++
+	n := sm.joinLine
+	for _, ch := range strings.Split(msg.Arg(0), ",") {
+		sm.joins[normalizeChannel(ch)] = n
+	}
+	sm.mu.Unlock()
+	if state != Handshaking {
+		i.setState(Joining, nil)
+	}
+
+	timeout := i.JoinTimeout
+	if timeout <= 0 {
+		timeout = DefaultJoinTimeout
+	}
+	expired := i.clock().After(timeout)
+	go func() {
+		<-expired
+		i.expireJoins(n)
+	}()
+}
+
+// expireJoins stops waiting for the channels of the nth JOIN line.
+func (i *IRCon) expireJoins(n uint64) {
+	sm := &i.sm
+	var expired []string
+	sm.mu.Lock()
+	for ch, v := range sm.joins {
+		if v == n {
+			delete(sm.joins, ch)
+			expired = append(expired, ch)
+		}
+	}
+	sm.mu.Unlock()
+	if len(expired) == 0 {
+		return
+	}
+	i.log().Warn("JOIN timed out", "channels", expired)
+	i.setStateIf(i.joined, Registered, nil)
+}
+
+// joined reports whether all JOINs are answered while Joining. The lock must
+// be held.
+func (i *IRCon) joined() bool {
+	return i.sm.state == Joining && len(i.sm.joins) == 0
+}
+
+// joining reports whether a JOIN to a channel is pending.
+func (i *IRCon) joining(channel string) bool {
+	i.sm.mu.Lock()
+	defer i.sm.mu.Unlock()
+	_, ok := i.sm.joins[normalizeChannel(channel)]
+	return ok
+}
+
+// track updates the state from an incoming message.
+func (i *IRCon) track(msg *irc.Message) {
+	var channel string
+	switch msg.Command {
+	case "001":
+		i.setState(Registered, nil)
+		i.setStateIf(func() bool {
+			return i.sm.state == Registered && len(i.sm.joins) > 0
+		}, Joining, nil)
+		return
+	case "JOIN":
+		nick := i.Self.Nick()
+		if nick == "" || !strings.HasPrefix(msg.Source, nick+"!") {
+			return
+		}
+		channel = msg.Arg(0)
+	case "NOTICE":
+		// A failed JOIN is answered with a NOTICE, e.g. msg_channel_suspended
+		if !joinFailures[msg.Tags["msg-id"]] {
+			return
+		}
+		channel = msg.Arg(0)
+	default:
+		return
+	}
+
+	sm := &i.sm
+	sm.mu.Lock()
+	delete(sm.joins, normalizeChannel(channel))
+	sm.mu.Unlock()
+	i.setStateIf(i.joined, Registered, nil)
+}
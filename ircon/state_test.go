@@ -0,0 +1,115 @@
+package ircon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/clock"
+)
+
+func TestStates(t *testing.T) {
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	i := New(d, TwitchHandshaker("bot", "oauth:x"))
+	i.Reconnect = Fixed{Delay: time.Hour}
+	transitions := make(chan Transition, 16)
+	i.Subscribe(func(t Transition) { transitions <- t })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r := newRecorder()
+	go i.Run(ctx, r)
+
+	c.in <- ":tmi.twitch.tv 001 bot :Welcome, GLHF!"
+	if err := i.WaitFor(ctx, Registered); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Send("JOIN #a,#b"); err != nil {
+		t.Fatal(err)
+	}
+	if s := i.State(); s != Joining {
+		t.Errorf("Wrong state: %v", s)
+	}
+	c.in <- ":bot!bot@bot.tmi.twitch.tv JOIN #a"
+	c.in <- ":bot!bot@bot.tmi.twitch.tv JOIN #b"
+	if err := i.WaitFor(ctx, Registered); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only JOIN failures end a pending JOIN
+	if err := i.Send("JOIN #c,#d"); err != nil {
+		t.Fatal(err)
+	}
+	c.in <- "@msg-id=msg_ratelimit :tmi.twitch.tv NOTICE #c :Your message was not sent because you are sending messages too quickly."
+	c.in <- "@msg-id=msg_channel_suspended :tmi.twitch.tv NOTICE #d :This channel has been suspended."
+	for msg := range r.messages {
+		if msg.Command == "NOTICE" && msg.Arg(0) == "#d" {
+			break
+		}
+	}
+	if s := i.State(); s != Joining {
+		t.Errorf("Wrong state: %v", s)
+	}
+	c.in <- ":bot!bot@bot.tmi.twitch.tv JOIN #c"
+	if err := i.WaitFor(ctx, Registered); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+	if err := i.WaitFor(ctx, BackingOff); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []State{Dialing, Handshaking, Registered, Joining, Registered, Joining, Registered, BackingOff}
+	for _, s := range want {
+		if tr := <-transitions; tr.To != s {
+			t.Errorf("Wrong transition: %v -> %v, expected %v", tr.From, tr.To, s)
+		}
+	}
+}
+
+func TestJoinStates(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	d := fakeDialer{conns: make(chan *fakeConn, 1)}
+	c := newFakeConn()
+	d.conns <- c
+
+	i := New(d, TwitchHandshaker("bot", "oauth:x"))
+	i.Clock = clk
+	i.JoinTimeout = 5 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r := newRecorder()
+	go i.Run(ctx, r)
+
+	// JOINs sent from Connected precede 001
+	<-r.connected
+	if err := i.Send("JOIN #a,#b"); err != nil {
+		t.Fatal(err)
+	}
+	if s := i.State(); s != Handshaking {
+		t.Errorf("Wrong state: %v", s)
+	}
+	c.in <- ":tmi.twitch.tv 001 bot :Welcome, GLHF!"
+	c.in <- ":bot!bot@bot.tmi.twitch.tv JOIN #a"
+	if err := i.WaitFor(ctx, Joining); err != nil {
+		t.Fatal(err)
+	}
+	for msg := range r.messages {
+		if msg.Command == "JOIN" {
+			break
+		}
+	}
+	if s := i.State(); s != Joining {
+		t.Errorf("Wrong state: %v", s)
+	}
+
+	// #b is never answered
+	clk.Advance(5 * time.Second)
+	if err := i.WaitFor(ctx, Registered); err != nil {
+		t.Fatal(err)
+	}
+}
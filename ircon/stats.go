@@ -0,0 +1,67 @@
+package ircon
+
+import (
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the Histogram buckets.
+var LatencyBuckets = []time.Duration{
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// A Histogram counts durations in LatencyBuckets. Counts has an extra final
+// bucket for longer durations.
+type Histogram struct {
+	Counts []uint64
+	Count  uint64
+	Sum    time.Duration
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	if h.Counts == nil {
+		h.Counts = make([]uint64, len(LatencyBuckets)+1)
+	}
+	n := 0
+	for n < len(LatencyBuckets) && d > LatencyBuckets[n] {
+		n++
+	}
+	h.Counts[n]++
+	h.Count++
+	h.Sum += d
+}
+
+// Mean returns the average duration.
+func (h *Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Stats are statistics of an IRCon.
+type Stats struct {
+	// Pings counts client PINGs, Pongs the matching PONGs received in time.
+	Pings uint64
+	Pongs uint64
+
+	// Latency is the round-trip time of client PINGs. LastLatency is the most
+	// recent.
+	Latency     Histogram
+	LastLatency time.Duration
+}
+
+// Stats returns a snapshot of the statistics.
+func (i *IRCon) Stats() Stats {
+	i.statsMu.Lock()
+	defer i.statsMu.Unlock()
+	s := i.stats
+	s.Latency.Counts = append([]uint64(nil), s.Latency.Counts...)
+	return s
+}
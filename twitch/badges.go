@@ -0,0 +1,59 @@
+package twitch
+
+import (
+	"strings"
+)
+
+// A Badge is a chat badge, e.g. subscriber/12. For badge-info the version
+// holds extra information such as the exact number of months subscribed.
+type Badge struct {
+	Name    string
+	Version string
+}
+
+// Badges is an ordered list of badges, as sent in the badges and badge-info
+// tags.
+type Badges []Badge
+
+// ParseBadges decodes a badge tag such as "moderator/1,subscriber/60".
+func ParseBadges(s string) Badges {
+	if s == "" {
+		return nil
+	}
+	var b Badges
+	for _, v := range strings.Split(s, ",") {
+		if v == "" {
+			continue
+		}
+		name, version := v, ""
+		if n := strings.IndexByte(v, '/'); n >= 0 {
+			name, version = v[:n], v[n+1:]
+		}
+		b = append(b, Badge{Name: name, Version: version})
+	}
+	return b
+}
+
+// Get returns the version of the named badge.
+func (b Badges) Get(name string) (string, bool) {
+	for _, badge := range b {
+		if badge.Name == name {
+			return badge.Version, true
+		}
+	}
+	return "", false
+}
+
+// Has reports whether the named badge is present.
+func (b Badges) Has(name string) bool {
+	_, ok := b.Get(name)
+	return ok
+}
+
+func (b Badges) String() string {
+	s := make([]string, len(b))
+	for i, badge := range b {
+		s[i] = badge.Name + "/" + badge.Version
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,60 @@
+package twitch
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An Emote is an emote occurrence in a message. Start and End are byte offsets
+// into the message text, so Text[Start:End] is the emote name.
+type Emote struct {
+	ID    string
+	Start int
+	End   int
+}
+
+// ParseEmotes decodes an emotes tag such as "25:0-4,12-16/1902:6-10" for the
+// given text. Twitch specifies positions as inclusive code point offsets;
+// these are converted to byte offsets. Positions outside of text are dropped.
+// The result is sorted by position.
+func ParseEmotes(s, text string) []Emote {
+	if s == "" {
+		return nil
+	}
+
+	// offsets maps code point index to byte offset, including the end.
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+
+	var emotes []Emote
+	for _, e := range strings.Split(s, "/") {
+		id, ranges := splitb(e, ':')
+		if id == "" {
+			continue
+		}
+		for _, r := range strings.Split(ranges, ",") {
+			a, b := splitb(r, '-')
+			start, err := strconv.Atoi(a)
+			if err != nil {
+				continue
+			}
+			end, err := strconv.Atoi(b)
+			if err != nil || start < 0 || end < start || end+1 >= len(offsets) {
+				continue
+			}
+			emotes = append(emotes, Emote{
+				ID:    id,
+				Start: offsets[start],
+				End:   offsets[end+1],
+			})
+		}
+	}
+	sort.Slice(emotes, func(i, j int) bool {
+		return emotes[i].Start < emotes[j].Start
+	})
+	return emotes
+}
@@ -0,0 +1,65 @@
+//go:build ignore
+// +build ignore
+
+// gen_notices generates notices_gen.go from notices.txt.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+)
+
+func main() {
+	f, err := os.Open("notices.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var rows [][]string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		row := strings.Split(line, "\t")
+		if len(row) != 4 {
+			log.Fatalf("Malformed line: %q", line)
+		}
+		rows = append(rows, row)
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// Code generated by gen_notices.go; DO NOT EDIT.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package twitch")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "var (")
+	for _, r := range rows {
+		fmt.Fprintf(&b, "%s = &NoticeError{MsgID: %q, Category: %s, Text: %q}\n", r[1], r[0], r[2], r[3])
+	}
+	fmt.Fprintln(&b, ")")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "var notices = map[string]*NoticeError{")
+	for _, r := range rows {
+		fmt.Fprintf(&b, "%q: %s,\n", r[0], r[1])
+	}
+	fmt.Fprintln(&b, "}")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("notices_gen.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+package twitch
+
+import (
+	"sync"
+)
+
+// A History keeps the most recent messages of every channel, so moderation
+// events can be resolved to the messages they affect. It is safe for
+// concurrent use.
+type History struct {
+	size     int
+	mu       sync.Mutex
+	channels map[string]*ring
+}
+
+// NewHistory creates a History keeping up to size messages per channel.
+func NewHistory(size int) *History {
+	return &History{
+		size:     size,
+		channels: make(map[string]*ring),
+	}
+}
+
+type ring struct {
+	msgs []*Privmsg
+	next int
+}
+
+// Add records a message.
+func (h *History) Add(p *Privmsg) {
+	if h.size <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.channels[p.Channel]
+	if r == nil {
+		r = &ring{}
+		h.channels[p.Channel] = r
+	}
+	if len(r.msgs) < h.size {
+		r.msgs = append(r.msgs, p)
+		return
+	}
+	r.msgs[r.next] = p
+	r.next = (r.next + 1) % h.size
+}
+
+// Find returns the message with the given id, or nil if it is not known.
+func (h *History) Find(channel, id string) *Privmsg {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r := h.channels[channel]; r != nil {
+		for _, p := range r.msgs {
+			if p.ID == id {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve returns the message removed by a ClearMsg.
+func (h *History) Resolve(c *ClearMsg) *Privmsg {
+	return h.Find(c.Channel, c.TargetMsgID)
+}
+
+// ByUser returns the known messages of a user in a channel, oldest first.
+// This resolves the messages removed by a ClearChat.
+func (h *History) ByUser(channel, user string) []*Privmsg {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.channels[channel]
+	if r == nil {
+		return nil
+	}
+	var res []*Privmsg
+	for i := range r.msgs {
+		p := r.msgs[(r.next+i)%len(r.msgs)]
+		if p.User == user {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Forget removes all messages of a channel, e.g. after parting it.
+func (h *History) Forget(channel string) {
+	h.mu.Lock()
+	delete(h.channels, channel)
+	h.mu.Unlock()
+}
@@ -0,0 +1,40 @@
+package twitch
+
+import (
+	"raccatta.cc/tmi/irc"
+)
+
+// A Join indicates a user joined a channel. Joins of other users are only
+// sent with the twitch.tv/membership capability.
+type Join struct {
+	Channel string
+	User    string
+
+	Message *irc.Message
+}
+
+// ParseJoin decodes a JOIN.
+func ParseJoin(m *irc.Message) *Join {
+	return &Join{
+		Channel: channel(m.Arg(0)),
+		User:    login(m.Source),
+		Message: m,
+	}
+}
+
+// A Part indicates a user left a channel.
+type Part struct {
+	Channel string
+	User    string
+
+	Message *irc.Message
+}
+
+// ParsePart decodes a PART.
+func ParsePart(m *irc.Message) *Part {
+	return &Part{
+		Channel: channel(m.Arg(0)),
+		User:    login(m.Source),
+		Message: m,
+	}
+}
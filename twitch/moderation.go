@@ -0,0 +1,75 @@
+package twitch
+
+import (
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A ClearChat removes all messages of a user from a channel, either as a ban
+// or a timeout, or removes all messages when User is empty.
+type ClearChat struct {
+	Channel string
+	RoomID  string
+	User    string // Login name of the target user
+	UserID  string
+	Time    time.Time
+
+	// Duration is the length of a timeout, or zero for a ban.
+	Duration time.Duration
+
+	Message *irc.Message
+}
+
+// ParseClearChat decodes a CLEARCHAT.
+func ParseClearChat(m *irc.Message) *ClearChat {
+	return &ClearChat{
+		Channel:  channel(m.Arg(0)),
+		RoomID:   m.Tags["room-id"],
+		User:     m.Trailer(1),
+		UserID:   m.Tags["target-user-id"],
+		Time:     tagTime(m.Tags, "tmi-sent-ts"),
+		Duration: time.Duration(tagInt(m.Tags, "ban-duration")) * time.Second,
+		Message:  m,
+	}
+}
+
+// FullClear reports whether all messages in the channel were removed.
+func (c *ClearChat) FullClear() bool {
+	return c.User == ""
+}
+
+// Ban reports whether the user was permanently banned.
+func (c *ClearChat) Ban() bool {
+	return c.User != "" && c.Duration == 0
+}
+
+// Timeout reports whether the user was temporarily banned.
+func (c *ClearChat) Timeout() bool {
+	return c.User != "" && c.Duration > 0
+}
+
+// A ClearMsg removes a single message from a channel.
+type ClearMsg struct {
+	Channel     string
+	RoomID      string
+	User        string // Login name of the author of the removed message
+	TargetMsgID string
+	Time        time.Time
+	Text        string // Text of the removed message
+
+	Message *irc.Message
+}
+
+// ParseClearMsg decodes a CLEARMSG.
+func ParseClearMsg(m *irc.Message) *ClearMsg {
+	return &ClearMsg{
+		Channel:     channel(m.Arg(0)),
+		RoomID:      m.Tags["room-id"],
+		User:        m.Tags["login"],
+		TargetMsgID: m.Tags["target-msg-id"],
+		Time:        tagTime(m.Tags, "tmi-sent-ts"),
+		Text:        m.Trailer(1),
+		Message:     m,
+	}
+}
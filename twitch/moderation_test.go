@@ -0,0 +1,44 @@
+package twitch
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestClearChat(t *testing.T) {
+	timeout := ParseClearChat(irc.ParseMessage("@ban-duration=350;room-id=12345678;target-user-id=87654321;tmi-sent-ts=1642715756806 :tmi.twitch.tv CLEARCHAT #dallas :ronni"))
+	if !timeout.Timeout() || timeout.Duration != 350*time.Second || timeout.UserID != "87654321" {
+		t.Errorf("Wrong timeout: %#v", timeout)
+	}
+	ban := ParseClearChat(irc.ParseMessage("@room-id=12345678;target-user-id=87654321;tmi-sent-ts=1642715756806 :tmi.twitch.tv CLEARCHAT #dallas :ronni"))
+	if !ban.Ban() || ban.User != "ronni" {
+		t.Errorf("Wrong ban: %#v", ban)
+	}
+	clear := ParseClearChat(irc.ParseMessage("@room-id=12345678;tmi-sent-ts=1642715695392 :tmi.twitch.tv CLEARCHAT #dallas"))
+	if !clear.FullClear() || clear.Ban() || clear.Timeout() {
+		t.Errorf("Wrong clear: %#v", clear)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(3)
+	for i := 0; i < 5; i++ {
+		h.Add(&Privmsg{Channel: "dallas", User: "ronni", ID: strconv.Itoa(i)})
+	}
+
+	c := ParseClearMsg(irc.ParseMessage("@login=ronni;room-id=;target-msg-id=3;tmi-sent-ts=1642720582342 :tmi.twitch.tv CLEARMSG #dallas :HeyGuys"))
+	if p := h.Resolve(c); p == nil || p.ID != "3" {
+		t.Errorf("Wrong message: %#v", p)
+	}
+	if p := h.Find("dallas", "1"); p != nil {
+		t.Errorf("Message should have been evicted: %#v", p)
+	}
+
+	msgs := h.ByUser("dallas", "ronni")
+	if len(msgs) != 3 || msgs[0].ID != "2" || msgs[2].ID != "4" {
+		t.Errorf("Wrong messages: %v", msgs)
+	}
+}
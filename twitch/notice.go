@@ -0,0 +1,103 @@
+package twitch
+
+//go:generate go run gen_notices.go
+
+import (
+	"raccatta.cc/tmi/irc"
+)
+
+// A Notice is a server message, typically in response to a command or a
+// rejected message. MsgID identifies the kind of notice.
+type Notice struct {
+	Channel string // Empty for notices not related to a channel
+	MsgID   string
+	Text    string
+
+	Message *irc.Message
+}
+
+// ParseNotice decodes a NOTICE.
+func ParseNotice(m *irc.Message) *Notice {
+	ch := m.Arg(0)
+	if ch == "*" {
+		ch = ""
+	}
+	return &Notice{
+		Channel: channel(ch),
+		MsgID:   m.Tags["msg-id"],
+		Text:    m.Trailer(1),
+		Message: m,
+	}
+}
+
+// Category returns the category of the notice, if it is a known rejection.
+func (n *Notice) Category() Category {
+	if e, ok := notices[n.MsgID]; ok {
+		return e.Category
+	}
+	return Other
+}
+
+// Known reports whether the notice is a rejection listed in the catalog.
+func (n *Notice) Known() bool {
+	_, ok := notices[n.MsgID]
+	return ok
+}
+
+// Err returns the notice as an error. It matches the corresponding catalog
+// error with errors.Is, e.g. ErrRateLimit, as well as its Category.
+func (n *Notice) Err() error {
+	return &NoticeError{MsgID: n.MsgID, Category: n.Category(), Text: n.Text}
+}
+
+// A Category groups notices by their cause. Categories are errors, so they
+// can be used as target with errors.Is.
+type Category int
+
+const (
+	Other Category = iota
+	RateLimit
+	Permission
+	ChannelState
+	Auth
+)
+
+func (c Category) String() string {
+	switch c {
+	case RateLimit:
+		return "rate limit"
+	case Permission:
+		return "permission"
+	case ChannelState:
+		return "channel state"
+	case Auth:
+		return "auth"
+	}
+	return "other"
+}
+
+func (c Category) Error() string {
+	return c.String()
+}
+
+// A NoticeError is a NOTICE rejecting a message or command.
+type NoticeError struct {
+	MsgID    string
+	Category Category
+	Text     string
+}
+
+func (e *NoticeError) Error() string {
+	return e.Text + " (" + e.MsgID + ")"
+}
+
+// Is matches notice errors with the same MsgID, and the Category.
+func (e *NoticeError) Is(target error) bool {
+	switch t := target.(type) {
+	case *NoticeError:
+		return t.MsgID == e.MsgID
+	case Category:
+		return t == e.Category
+	}
+	return false
+}
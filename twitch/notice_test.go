@@ -0,0 +1,24 @@
+package twitch
+
+import (
+	"errors"
+	"testing"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestNoticeErr(t *testing.T) {
+	n := ParseNotice(irc.ParseMessage("@msg-id=msg_ratelimit :tmi.twitch.tv NOTICE #bar :Your message was not sent because you are sending messages too quickly."))
+	err := n.Err()
+	if !errors.Is(err, ErrRateLimit) || !errors.Is(err, RateLimit) {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if errors.Is(err, ErrDuplicate) || errors.Is(err, Permission) {
+		t.Errorf("Wrong match: %v", err)
+	}
+
+	n = ParseNotice(irc.ParseMessage("@msg-id=msg_something_new :tmi.twitch.tv NOTICE #bar :Huh"))
+	if n.Category() != Other || !errors.Is(n.Err(), Other) {
+		t.Errorf("Wrong category: %v", n.Category())
+	}
+}
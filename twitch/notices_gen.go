@@ -0,0 +1,47 @@
+// Code generated by gen_notices.go; DO NOT EDIT.
+
+package twitch
+
+var (
+	ErrBanned                = &NoticeError{MsgID: "msg_banned", Category: Permission, Text: "You are permanently banned from talking in this channel."}
+	ErrBadCharacters         = &NoticeError{MsgID: "msg_bad_characters", Category: Other, Text: "Your message was not sent because it contained too many unprocessable characters."}
+	ErrChannelBlocked        = &NoticeError{MsgID: "msg_channel_blocked", Category: Permission, Text: "Your message was not sent because your account is not in good standing in this channel."}
+	ErrChannelSuspended      = &NoticeError{MsgID: "msg_channel_suspended", Category: ChannelState, Text: "This channel does not exist or has been suspended."}
+	ErrDuplicate             = &NoticeError{MsgID: "msg_duplicate", Category: RateLimit, Text: "Your message was not sent because it is identical to the previous one you sent, less than 30 seconds ago."}
+	ErrEmoteOnly             = &NoticeError{MsgID: "msg_emoteonly", Category: ChannelState, Text: "This room is in emote-only mode."}
+	ErrFollowersOnly         = &NoticeError{MsgID: "msg_followersonly", Category: ChannelState, Text: "This room is in followers-only mode."}
+	ErrFollowersOnlyFollowed = &NoticeError{MsgID: "msg_followersonly_followed", Category: ChannelState, Text: "This room is in followers-only mode and you have not followed long enough."}
+	ErrFollowersOnlyZero     = &NoticeError{MsgID: "msg_followersonly_zero", Category: ChannelState, Text: "This room is in followers-only mode."}
+	ErrR9K                   = &NoticeError{MsgID: "msg_r9k", Category: ChannelState, Text: "This room is in unique-chat mode and the message you attempted to send is not unique."}
+	ErrRateLimit             = &NoticeError{MsgID: "msg_ratelimit", Category: RateLimit, Text: "Your message was not sent because you are sending messages too quickly."}
+	ErrRejected              = &NoticeError{MsgID: "msg_rejected", Category: Permission, Text: "Your message is being checked by mods and has not been sent."}
+	ErrRejectedMandatory     = &NoticeError{MsgID: "msg_rejected_mandatory", Category: Permission, Text: "Your message wasn't posted due to conflicts with the channel's moderation settings."}
+	ErrRequiresVerifiedPhone = &NoticeError{MsgID: "msg_requires_verified_phone_number", Category: Auth, Text: "A verified phone number is required to chat in this channel."}
+	ErrSlowMode              = &NoticeError{MsgID: "msg_slowmode", Category: ChannelState, Text: "This room is in slow mode and you are sending messages too quickly."}
+	ErrSubsOnly              = &NoticeError{MsgID: "msg_subsonly", Category: ChannelState, Text: "This room is in subscribers only mode."}
+	ErrSuspended             = &NoticeError{MsgID: "msg_suspended", Category: Auth, Text: "You don't have permission to perform that action."}
+	ErrTimedOut              = &NoticeError{MsgID: "msg_timedout", Category: Permission, Text: "You are timed out."}
+	ErrVerifiedEmail         = &NoticeError{MsgID: "msg_verified_email", Category: Auth, Text: "This room requires a verified account to chat."}
+)
+
+var notices = map[string]*NoticeError{
+	"msg_banned":                         ErrBanned,
+	"msg_bad_characters":                 ErrBadCharacters,
+	"msg_channel_blocked":                ErrChannelBlocked,
+	"msg_channel_suspended":              ErrChannelSuspended,
+	"msg_duplicate":                      ErrDuplicate,
+	"msg_emoteonly":                      ErrEmoteOnly,
+	"msg_followersonly":                  ErrFollowersOnly,
+	"msg_followersonly_followed":         ErrFollowersOnlyFollowed,
+	"msg_followersonly_zero":             ErrFollowersOnlyZero,
+	"msg_r9k":                            ErrR9K,
+	"msg_ratelimit":                      ErrRateLimit,
+	"msg_rejected":                       ErrRejected,
+	"msg_rejected_mandatory":             ErrRejectedMandatory,
+	"msg_requires_verified_phone_number": ErrRequiresVerifiedPhone,
+	"msg_slowmode":                       ErrSlowMode,
+	"msg_subsonly":                       ErrSubsOnly,
+	"msg_suspended":                      ErrSuspended,
+	"msg_timedout":                       ErrTimedOut,
+	"msg_verified_email":                 ErrVerifiedEmail,
+}
@@ -0,0 +1,100 @@
+package twitch
+
+import (
+	"strings"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A Privmsg is a chat message sent to a channel.
+type Privmsg struct {
+	Channel     string
+	RoomID      string
+	User        string // Login name of the sender
+	DisplayName string
+	UserID      string
+	Color       string
+	ID          string // Unique message id, used for replies and deletions
+	Time        time.Time
+
+	// Text is the message text. For an ACTION (/me) the CTCP framing is
+	// removed and Action is set.
+	Text   string
+	Action bool
+
+	Badges    Badges
+	BadgeInfo Badges
+	Emotes    []Emote
+
+	Bits             int
+	FirstMsg         bool
+	ReturningChatter bool
+
+	// Reply is set when the message is a reply to another message.
+	Reply *Reply
+
+	Message *irc.Message
+}
+
+// A Reply refers to the message a Privmsg replied to.
+type Reply struct {
+	ParentMsgID       string
+	ParentUserID      string
+	ParentUser        string
+	ParentDisplayName string
+	ParentText        string
+
+	// Thread refers to the first message of a reply thread.
+	ThreadParentMsgID string
+	ThreadParentUser  string
+}
+
+// ParsePrivmsg decodes a PRIVMSG.
+func ParsePrivmsg(m *irc.Message) *Privmsg {
+	text, action := parseAction(m.Trailer(1))
+	return &Privmsg{
+		Channel:          channel(m.Arg(0)),
+		RoomID:           m.Tags["room-id"],
+		User:             login(m.Source),
+		DisplayName:      m.Tags["display-name"],
+		UserID:           m.Tags["user-id"],
+		Color:            m.Tags["color"],
+		ID:               m.Tags["id"],
+		Time:             tagTime(m.Tags, "tmi-sent-ts"),
+		Text:             text,
+		Action:           action,
+		Badges:           ParseBadges(m.Tags["badges"]),
+		BadgeInfo:        ParseBadges(m.Tags["badge-info"]),
+		Emotes:           ParseEmotes(m.Tags["emotes"], text),
+		Bits:             tagInt(m.Tags, "bits"),
+		FirstMsg:         tagBool(m.Tags, "first-msg"),
+		ReturningChatter: tagBool(m.Tags, "returning-chatter"),
+		Reply:            parseReply(m.Tags),
+		Message:          m,
+	}
+}
+
+func parseAction(s string) (string, bool) {
+	const prefix = "\x01ACTION "
+	if strings.HasPrefix(s, prefix) {
+		return strings.TrimSuffix(s[len(prefix):], "\x01"), true
+	}
+	return s, false
+}
+
+func parseReply(tags map[string]string) *Reply {
+	id := tags["reply-parent-msg-id"]
+	if id == "" {
+		return nil
+	}
+	return &Reply{
+		ParentMsgID:       id,
+		ParentUserID:      tags["reply-parent-user-id"],
+		ParentUser:        tags["reply-parent-user-login"],
+		ParentDisplayName: tags["reply-parent-display-name"],
+		ParentText:        tags["reply-parent-msg-body"],
+		ThreadParentMsgID: tags["reply-thread-parent-msg-id"],
+		ThreadParentUser:  tags["reply-thread-parent-user-login"],
+	}
+}
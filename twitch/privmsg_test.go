@@ -0,0 +1,56 @@
+package twitch
+
+import (
+	"testing"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestPrivmsg(t *testing.T) {
+	m := ParsePrivmsg(irc.ParseMessage("@badge-info=subscriber/68;badges=moderator/1,subscriber/60;bits=100;color=#0D4200;display-name=TWITCH_UserNaME;emotes=25:0-4,12-16/1902:6-10;first-msg=1;id=b34ccfc7-4977-403a-8a94-33c6bac34fb8;room-id=1337;tmi-sent-ts=1507246572675;user-id=1234 :twitch_username!twitch_username@twitch_username.tmi.twitch.tv PRIVMSG #channel :Kappa Keepo Kappa"))
+
+	if m.Channel != "channel" || m.User != "twitch_username" || m.Text != "Kappa Keepo Kappa" {
+		t.Errorf("Wrong message: %#v", m)
+	}
+	if v, _ := m.BadgeInfo.Get("subscriber"); v != "68" {
+		t.Errorf("Wrong badge-info: %v", m.BadgeInfo)
+	}
+	if !m.Badges.Has("moderator") || m.Badges.String() != "moderator/1,subscriber/60" {
+		t.Errorf("Wrong badges: %v", m.Badges)
+	}
+	if m.Bits != 100 || !m.FirstMsg || m.ReturningChatter || m.Reply != nil {
+		t.Errorf("Wrong flags: %#v", m)
+	}
+	if m.Time.UnixNano() != 1507246572675*1e6 {
+		t.Errorf("Wrong time: %v", m.Time)
+	}
+
+	names := []string{"Kappa", "Keepo", "Kappa"}
+	if len(m.Emotes) != len(names) {
+		t.Fatalf("Wrong emotes: %v", m.Emotes)
+	}
+	for i, e := range m.Emotes {
+		if n := m.Text[e.Start:e.End]; n != names[i] {
+			t.Errorf("Wrong emote %d: %q", i, n)
+		}
+	}
+}
+
+func TestPrivmsgAction(t *testing.T) {
+	m := ParsePrivmsg(irc.ParseMessage("@emotes=25:8-12;reply-parent-msg-id=abc;reply-parent-user-login=foo :bar!bar@bar.tmi.twitch.tv PRIVMSG #channel :\x01ACTION 🙂 héllo Kappa 🙂\x01"))
+	if !m.Action || m.Text != "🙂 héllo Kappa 🙂" {
+		t.Errorf("Wrong action: %q", m.Text)
+	}
+	if len(m.Emotes) != 1 || m.Text[m.Emotes[0].Start:m.Emotes[0].End] != "Kappa" {
+		t.Errorf("Wrong emotes: %v", m.Emotes)
+	}
+	if m.Reply == nil || m.Reply.ParentMsgID != "abc" || m.Reply.ParentUser != "foo" {
+		t.Errorf("Wrong reply: %#v", m.Reply)
+	}
+}
+
+func TestEmotesOutOfRange(t *testing.T) {
+	if e := ParseEmotes("25:0-4,6-100/x:a-b", "Kappa"); len(e) != 1 {
+		t.Errorf("Wrong emotes: %v", e)
+	}
+}
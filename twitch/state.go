@@ -0,0 +1,153 @@
+package twitch
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A RoomState describes the chat settings of a channel. TMI sends the full
+// state on join and only the changed settings afterwards; Apply merges these.
+type RoomState struct {
+	Channel string
+	RoomID  string
+
+	EmoteOnly bool
+	R9K       bool
+	SubsOnly  bool
+
+	// FollowersOnly is set when only followers can chat. FollowersMin is the
+	// time a user needs to have been following.
+	FollowersOnly bool
+	FollowersMin  time.Duration
+
+	// Slow is the minimum time between messages of a user, or zero.
+	Slow time.Duration
+
+	Message *irc.Message
+}
+
+// ParseRoomState decodes a ROOMSTATE. Settings not present in the message are
+// left at their zero value.
+func ParseRoomState(m *irc.Message) *RoomState {
+	s := &RoomState{}
+	s.Apply(m)
+	return s
+}
+
+// Apply updates the state with the settings present in a ROOMSTATE.
+func (s *RoomState) Apply(m *irc.Message) {
+	s.Channel = channel(m.Arg(0))
+	s.Message = m
+	if v, ok := m.Tags["room-id"]; ok {
+		s.RoomID = v
+	}
+	if _, ok := m.Tags["emote-only"]; ok {
+		s.EmoteOnly = tagBool(m.Tags, "emote-only")
+	}
+	if _, ok := m.Tags["r9k"]; ok {
+		s.R9K = tagBool(m.Tags, "r9k")
+	}
+	if _, ok := m.Tags["subs-only"]; ok {
+		s.SubsOnly = tagBool(m.Tags, "subs-only")
+	}
+	if v, ok := m.Tags["followers-only"]; ok {
+		// -1 disables followers-only, otherwise it is the duration in minutes
+		n, err := strconv.Atoi(v)
+		s.FollowersOnly = err == nil && n >= 0
+		s.FollowersMin = 0
+		if s.FollowersOnly {
+			s.FollowersMin = time.Duration(n) * time.Minute
+		}
+	}
+	if _, ok := m.Tags["slow"]; ok {
+		s.Slow = time.Duration(tagInt(m.Tags, "slow")) * time.Second
+	}
+}
+
+// A UserState describes the connected user in a channel. It is sent on join
+// and after every message sent.
+type UserState struct {
+	Channel     string
+	DisplayName string
+	Color       string
+	ID          string // Id of the message sent, if any
+	ClientNonce string
+
+	Badges    Badges
+	BadgeInfo Badges
+	EmoteSets []string
+
+	Message *irc.Message
+}
+
+// ParseUserState decodes a USERSTATE.
+func ParseUserState(m *irc.Message) *UserState {
+	return &UserState{
+		Channel:     channel(m.Arg(0)),
+		DisplayName: m.Tags["display-name"],
+		Color:       m.Tags["color"],
+		ID:          m.Tags["id"],
+		ClientNonce: m.Tags["client-nonce"],
+		Badges:      ParseBadges(m.Tags["badges"]),
+		BadgeInfo:   ParseBadges(m.Tags["badge-info"]),
+		EmoteSets:   parseList(m.Tags["emote-sets"]),
+		Message:     m,
+	}
+}
+
+// Broadcaster reports whether the user owns the channel.
+func (s *UserState) Broadcaster() bool {
+	return s.Badges.Has("broadcaster")
+}
+
+// Moderator reports whether the user can moderate the channel, which includes
+// the broadcaster.
+func (s *UserState) Moderator() bool {
+	return s.Badges.Has("moderator") || s.Broadcaster()
+}
+
+// VIP reports whether the user is a VIP in the channel.
+func (s *UserState) VIP() bool {
+	return s.Badges.Has("vip")
+}
+
+// Subscriber reports whether the user is subscribed to the channel.
+func (s *UserState) Subscriber() bool {
+	return s.Badges.Has("subscriber") || s.Badges.Has("founder")
+}
+
+// A GlobalUserState describes the connected user after logging in.
+type GlobalUserState struct {
+	UserID      string
+	DisplayName string
+	Color       string
+
+	Badges    Badges
+	BadgeInfo Badges
+	EmoteSets []string
+
+	Message *irc.Message
+}
+
+// ParseGlobalUserState decodes a GLOBALUSERSTATE.
+func ParseGlobalUserState(m *irc.Message) *GlobalUserState {
+	return &GlobalUserState{
+		UserID:      m.Tags["user-id"],
+		DisplayName: m.Tags["display-name"],
+		Color:       m.Tags["color"],
+		Badges:      ParseBadges(m.Tags["badges"]),
+		BadgeInfo:   ParseBadges(m.Tags["badge-info"]),
+		EmoteSets:   parseList(m.Tags["emote-sets"]),
+		Message:     m,
+	}
+}
+
+func parseList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
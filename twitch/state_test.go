@@ -0,0 +1,26 @@
+package twitch
+
+import (
+	"testing"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestRoomState(t *testing.T) {
+	s := ParseRoomState(irc.ParseMessage("@emote-only=0;followers-only=-1;r9k=0;room-id=12345678;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #bar"))
+	if s.Channel != "bar" || s.RoomID != "12345678" || s.FollowersOnly || s.Slow != 0 {
+		t.Errorf("Wrong state: %#v", s)
+	}
+
+	s.Apply(irc.ParseMessage("@room-id=12345678;slow=10 :tmi.twitch.tv ROOMSTATE #bar"))
+	s.Apply(irc.ParseMessage("@followers-only=10;room-id=12345678 :tmi.twitch.tv ROOMSTATE #bar"))
+	if s.Slow != 10*time.Second || !s.FollowersOnly || s.FollowersMin != 10*time.Minute || s.SubsOnly {
+		t.Errorf("Wrong state: %#v", s)
+	}
+
+	s.Apply(irc.ParseMessage("@followers-only=-1;room-id=12345678 :tmi.twitch.tv ROOMSTATE #bar"))
+	if s.FollowersOnly || s.Slow != 10*time.Second {
+		t.Errorf("Wrong state: %#v", s)
+	}
+}
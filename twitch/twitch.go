@@ -0,0 +1,52 @@
+// Package twitch decodes the Twitch-specific messages of the Twitch Messaging
+// Interface into typed structures.
+//
+// Decoding is lenient: missing or malformed tags result in zero values rather
+// than errors, mirroring irc.ParseMessage.
+package twitch
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// channel returns a channel argument without its leading '#'.
+func channel(arg string) string {
+	return strings.TrimPrefix(arg, "#")
+}
+
+// login extracts the nickname from a source of the form nick!user@host. The
+// server itself (e.g. tmi.twitch.tv) has no nickname.
+func login(source string) string {
+	if n := strings.IndexByte(source, '!'); n >= 0 {
+		return source[:n]
+	}
+	return ""
+}
+
+func splitb(s string, b byte) (string, string) {
+	c := strings.IndexByte(s, b)
+	if c == -1 {
+		return s, ""
+	}
+	return s[:c], s[c+1:]
+}
+
+func tagInt(tags map[string]string, key string) int {
+	n, _ := strconv.Atoi(tags[key])
+	return n
+}
+
+func tagBool(tags map[string]string, key string) bool {
+	return tags[key] == "1"
+}
+
+// tagTime decodes a timestamp in milliseconds, such as tmi-sent-ts.
+func tagTime(tags map[string]string, key string) time.Time {
+	ms, err := strconv.ParseInt(tags[key], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
@@ -0,0 +1,274 @@
+package twitch
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"raccatta.cc/tmi/irc"
+)
+
+// A UserNotice announces events such as subscriptions and raids. The kind of
+// event is identified by MsgID and decoded into Event.
+type UserNotice struct {
+	Channel     string
+	RoomID      string
+	MsgID       string
+	User        string // Login name of the user causing the event
+	DisplayName string
+	UserID      string
+	Color       string
+	ID          string
+	Time        time.Time
+	SystemMsg   string // Human readable description of the event
+	Text        string // Optional message supplied by the user
+
+	Badges    Badges
+	BadgeInfo Badges
+	Emotes    []Emote
+
+	// Params holds all msg-param-* tags, without the prefix.
+	Params map[string]string
+
+	// Event is the decoded event. It is an *Unknown for unrecognized MsgIDs.
+	Event Event
+
+	Message *irc.Message
+}
+
+// ParseUserNotice decodes a USERNOTICE.
+func ParseUserNotice(m *irc.Message) *UserNotice {
+	const prefix = "msg-param-"
+	params := make(map[string]string)
+	for k, v := range m.Tags {
+		if strings.HasPrefix(k, prefix) {
+			params[k[len(prefix):]] = v
+		}
+	}
+
+	n := &UserNotice{
+		Channel:     channel(m.Arg(0)),
+		RoomID:      m.Tags["room-id"],
+		MsgID:       m.Tags["msg-id"],
+		User:        m.Tags["login"],
+		DisplayName: m.Tags["display-name"],
+		UserID:      m.Tags["user-id"],
+		Color:       m.Tags["color"],
+		ID:          m.Tags["id"],
+		Time:        tagTime(m.Tags, "tmi-sent-ts"),
+		SystemMsg:   m.Tags["system-msg"],
+		Text:        m.Trailer(1),
+		Badges:      ParseBadges(m.Tags["badges"]),
+		BadgeInfo:   ParseBadges(m.Tags["badge-info"]),
+		Params:      params,
+		Message:     m,
+	}
+	n.Emotes = ParseEmotes(m.Tags["emotes"], n.Text)
+	n.Event = parseEvent(n.MsgID, params)
+	return n
+}
+
+// An Event is the decoded content of a UserNotice.
+type Event interface {
+	event()
+}
+
+// Sub is a new subscription (msg-id sub).
+type Sub struct {
+	CumulativeMonths   int
+	StreakMonths       int // Only set if ShouldShareStreak
+	ShouldShareStreak  bool
+	SubPlan            string // Prime, 1000, 2000 or 3000
+	SubPlanName        string
+	MultiMonthDuration int
+}
+
+// Resub is a renewed subscription (msg-id resub).
+type Resub Sub
+
+// SubGift is a subscription gifted to a specific user (msg-id subgift).
+type SubGift struct {
+	Months               int
+	GiftMonths           int
+	RecipientUser        string
+	RecipientDisplayName string
+	RecipientID          string
+	SubPlan              string
+	SubPlanName          string
+}
+
+// SubMysteryGift is a set of subscriptions gifted to random users (msg-id
+// submysterygift). It is followed by a SubGift for every recipient.
+type SubMysteryGift struct {
+	MassGiftCount int
+	SenderCount   int // Total gifts of the sender in the channel
+	SubPlan       string
+}
+
+// GiftPaidUpgrade is a gifted subscription continued by the user (msg-id
+// giftpaidupgrade).
+type GiftPaidUpgrade struct {
+	PromoGiftTotal int
+	PromoName      string
+	SenderUser     string
+	SenderName     string
+}
+
+// AnonGiftPaidUpgrade is an anonymously gifted subscription continued by the
+// user (msg-id anongiftpaidupgrade).
+type AnonGiftPaidUpgrade struct {
+	PromoGiftTotal int
+	PromoName      string
+}
+
+// RewardGift is a reward shared with the chat (msg-id rewardgift).
+type RewardGift struct {
+	Domain           string
+	SelectedCount    int
+	TotalRewardCount int
+	TriggerAmount    int
+	TriggerType      string
+}
+
+// Raid is an incoming raid (msg-id raid).
+type Raid struct {
+	User            string
+	DisplayName     string
+	ViewerCount     int
+	ProfileImageURL string
+}
+
+// Unraid is a cancelled raid (msg-id unraid).
+type Unraid struct{}
+
+// Ritual is a ritual such as a new chatter introducing themself (msg-id
+// ritual).
+type Ritual struct {
+	RitualName string
+}
+
+// BitsBadgeTier is a newly earned bits badge (msg-id bitsbadgetier).
+type BitsBadgeTier struct {
+	Threshold int
+}
+
+// Announcement is a highlighted message by a moderator (msg-id
+// announcement).
+type Announcement struct {
+	Color string // PRIMARY, BLUE, GREEN, ORANGE or PURPLE
+}
+
+// ViewerMilestone is a shared viewer milestone such as a watch streak
+// (msg-id viewermilestone).
+type ViewerMilestone struct {
+	Category   string
+	ID         string
+	Value      int
+	CopoReward int // Channel points rewarded
+}
+
+// Unknown is an event with an unrecognized msg-id.
+type Unknown struct {
+	MsgID  string
+	Params map[string]string
+}
+
+func (*Sub) event()                 {}
+func (*Resub) event()               {}
+func (*SubGift) event()             {}
+func (*SubMysteryGift) event()      {}
+func (*GiftPaidUpgrade) event()     {}
+func (*AnonGiftPaidUpgrade) event() {}
+func (*RewardGift) event()          {}
+func (*Raid) event()                {}
+func (*Unraid) event()              {}
+func (*Ritual) event()              {}
+func (*BitsBadgeTier) event()       {}
+func (*Announcement) event()        {}
+func (*ViewerMilestone) event()     {}
+func (*Unknown) event()             {}
+
+type params map[string]string
+
+func (p params) int(key string) int {
+	n, _ := strconv.Atoi(p[key])
+	return n
+}
+
+func parseEvent(msgID string, tags map[string]string) Event {
+	p := params(tags)
+	switch msgID {
+	case "sub", "resub":
+		sub := Sub{
+			CumulativeMonths:   p.int("cumulative-months"),
+			StreakMonths:       p.int("streak-months"),
+			ShouldShareStreak:  p["should-share-streak"] == "1",
+			SubPlan:            p["sub-plan"],
+			SubPlanName:        p["sub-plan-name"],
+			MultiMonthDuration: p.int("multimonth-duration"),
+		}
+		if msgID == "resub" {
+			return (*Resub)(&sub)
+		}
+		return &sub
+	case "subgift":
+		return &SubGift{
+			Months:               p.int("months"),
+			GiftMonths:           p.int("gift-months"),
+			RecipientUser:        p["recipient-user-name"],
+			RecipientDisplayName: p["recipient-display-name"],
+			RecipientID:          p["recipient-id"],
+			SubPlan:              p["sub-plan"],
+			SubPlanName:          p["sub-plan-name"],
+		}
+	case "submysterygift":
+		return &SubMysteryGift{
+			MassGiftCount: p.int("mass-gift-count"),
+			SenderCount:   p.int("sender-count"),
+			SubPlan:       p["sub-plan"],
+		}
+	case "giftpaidupgrade":
+		return &GiftPaidUpgrade{
+			PromoGiftTotal: p.int("promo-gift-total"),
+			PromoName:      p["promo-name"],
+			SenderUser:     p["sender-login"],
+			SenderName:     p["sender-name"],
+		}
+	case "anongiftpaidupgrade":
+		return &AnonGiftPaidUpgrade{
+			PromoGiftTotal: p.int("promo-gift-total"),
+			PromoName:      p["promo-name"],
+		}
+	case "rewardgift":
+		return &RewardGift{
+			Domain:           p["domain"],
+			SelectedCount:    p.int("selected-count"),
+			TotalRewardCount: p.int("total-reward-count"),
+			TriggerAmount:    p.int("trigger-amount"),
+			TriggerType:      p["trigger-type"],
+		}
+	case "raid":
+		return &Raid{
+			User:            p["login"],
+			DisplayName:     p["displayName"],
+			ViewerCount:     p.int("viewerCount"),
+			ProfileImageURL: p["profileImageURL"],
+		}
+	case "unraid":
+		return &Unraid{}
+	case "ritual":
+		return &Ritual{RitualName: p["ritual-name"]}
+	case "bitsbadgetier":
+		return &BitsBadgeTier{Threshold: p.int("threshold")}
+	case "announcement":
+		return &Announcement{Color: p["color"]}
+	case "viewermilestone":
+		return &ViewerMilestone{
+			Category:   p["category"],
+			ID:         p["id"],
+			Value:      p.int("value"),
+			CopoReward: p.int("copoReward"),
+		}
+	}
+	return &Unknown{MsgID: msgID, Params: tags}
+}
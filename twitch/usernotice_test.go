@@ -0,0 +1,34 @@
+package twitch
+
+import (
+	"testing"
+
+	"raccatta.cc/tmi/irc"
+)
+
+func TestUserNotice(t *testing.T) {
+	resub := ParseUserNotice(irc.ParseMessage("@badge-info=subscriber/68;badges=subscriber/60;color=#6B00B8;display-name=MrXtacle;emotes=;flags=;id=16894214-c514-4612-a979-de9280ca437a;login=mrxtacle;mod=0;msg-id=resub;msg-param-cumulative-months=68;msg-param-months=0;msg-param-should-share-streak=0;msg-param-sub-plan-name=Baka\\sBrigade!;msg-param-sub-plan=1000;room-id=24761645;subscriber=1;system-msg=MrXtacle\\ssubscribed\\sat\\sTier\\s1.\\sThey've\\ssubscribed\\sfor\\s68\\smonths!;tmi-sent-ts=1578181991964;user-id=28413930;user-type= :tmi.twitch.tv USERNOTICE #cirno_tv :Look at me, I'm all gwown up RainbowDaijoubu"))
+	if e, ok := resub.Event.(*Resub); !ok {
+		t.Errorf("Wrong event: %#v", resub.Event)
+	} else if e.CumulativeMonths != 68 || e.SubPlan != "1000" || e.SubPlanName != "Baka Brigade!" {
+		t.Errorf("Wrong resub: %#v", e)
+	}
+	if resub.User != "mrxtacle" || resub.Text != "Look at me, I'm all gwown up RainbowDaijoubu" {
+		t.Errorf("Wrong notice: %#v", resub)
+	}
+
+	raid := ParseUserNotice(irc.ParseMessage("@badge-info=;badges=glhf-pledge/1;color=#1E90FF;display-name=radekdarade;emotes=;flags=;id=28f21eab-53f3-4ccd-aeee-c43dcad491a1;login=radekdarade;mod=0;msg-id=raid;msg-param-displayName=radekdarade;msg-param-login=radekdarade;msg-param-profileImageURL=https://static-cdn.jtvnw.net/jtv_user_pictures/70f6d58c-1f74-47c7-96bb-7e2e931c0e36-profile_image-70x70.png;msg-param-viewerCount=1;room-id=22484632;subscriber=0;system-msg=1\\sraiders\\sfrom\\sradekdarade\\shave\\sjoined!;tmi-sent-ts=1605002353635;user-id=208475120;user-type= :tmi.twitch.tv USERNOTICE #forsen"))
+	if e, ok := raid.Event.(*Raid); !ok {
+		t.Errorf("Wrong event: %#v", raid.Event)
+	} else if e.User != "radekdarade" || e.ViewerCount != 1 {
+		t.Errorf("Wrong raid: %#v", e)
+	}
+	if raid.Text != "" {
+		t.Errorf("Wrong text: %q", raid.Text)
+	}
+
+	unknown := ParseUserNotice(irc.ParseMessage("@msg-id=newthing;msg-param-foo=bar :tmi.twitch.tv USERNOTICE #forsen"))
+	if e, ok := unknown.Event.(*Unknown); !ok || e.MsgID != "newthing" || e.Params["foo"] != "bar" {
+		t.Errorf("Wrong event: %#v", unknown.Event)
+	}
+}
@@ -0,0 +1,32 @@
+package twitch
+
+import (
+	"raccatta.cc/tmi/irc"
+)
+
+// A Whisper is a private message to the connected user.
+type Whisper struct {
+	From        string // Login name of the sender
+	To          string // Login name of the recipient
+	DisplayName string
+	UserID      string
+	ID          string
+	ThreadID    string
+	Text        string
+
+	Message *irc.Message
+}
+
+// ParseWhisper decodes a WHISPER.
+func ParseWhisper(m *irc.Message) *Whisper {
+	return &Whisper{
+		From:        login(m.Source),
+		To:          m.Arg(0),
+		DisplayName: m.Tags["display-name"],
+		UserID:      m.Tags["user-id"],
+		ID:          m.Tags["message-id"],
+		ThreadID:    m.Tags["thread-id"],
+		Text:        m.Trailer(1),
+		Message:     m,
+	}
+}